@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Formats of the logs.
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// setupLogging sets the format and level of the logs of all packages.
+func setupLogging(format, level string) error {
+	switch format {
+	case logFormatText:
+		logrus.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	case logFormatJSON:
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	default:
+		return fmt.Errorf("invalid log format %q", format)
+	}
+
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	logrus.SetLevel(lvl)
+	return nil
+}
+
+// logLevelHandler responds with the log level. PUT requests change the
+// level to the level form value, e.g.
+// curl -X PUT -d level=debug localhost:8080/log-level
+func logLevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			lvl, err := logrus.ParseLevel(r.FormValue("level"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if lvl != logrus.GetLevel() {
+				log.WithFields(logrus.Fields{"from": logrus.GetLevel(), "to": lvl}).Warning("Changing log level")
+				logrus.SetLevel(lvl)
+			}
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		fmt.Fprintln(w, logrus.GetLevel())
+	})
+}
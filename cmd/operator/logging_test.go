@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetupLogging(t *testing.T) {
+	defer logrus.SetLevel(logrus.GetLevel())
+
+	assert.Nil(t, setupLogging(logFormatJSON, "DEBUG"))
+	assert.Equal(t, logrus.DebugLevel, logrus.GetLevel())
+	assert.NotNil(t, setupLogging("xml", "info"))
+	assert.NotNil(t, setupLogging(logFormatText, "loud"))
+	assert.Nil(t, setupLogging(logFormatText, "info"))
+}
+
+func TestLogLevelHandler(t *testing.T) {
+	defer logrus.SetLevel(logrus.GetLevel())
+	logrus.SetLevel(logrus.InfoLevel)
+	h := logLevelHandler()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/log-level", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "info\n", w.Body.String())
+
+	r := httptest.NewRequest(http.MethodPut, "/log-level", strings.NewReader("level=debug"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "debug\n", w.Body.String())
+	assert.Equal(t, logrus.DebugLevel, logrus.GetLevel())
+
+	r = httptest.NewRequest(http.MethodPut, "/log-level?level=loud", nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, logrus.DebugLevel, logrus.GetLevel())
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/log-level", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
@@ -2,50 +2,133 @@ package main
 
 import (
 	"context"
+	"flag"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
-	"github.com/op/go-logging"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
 	"golang.org/x/sync/errgroup"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/pkg/api/v1"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 
+	"github.com/rossf7/pingdom-operator/pkg/election"
 	"github.com/rossf7/pingdom-operator/pkg/pingdom"
 	"github.com/rossf7/pingdom-operator/pkg/tpr"
 )
 
 var (
-	log = logging.MustGetLogger("cmd")
+	log = logrus.WithField("component", "cmd")
 )
 
 func Main() int {
+	opts := defaultOptions()
+	if err := opts.parse(flag.CommandLine, os.Args[1:]); err != nil {
+		log.WithError(err).Error("Error parsing options")
+		return 1
+	}
+	if err := setupLogging(opts.LogFormat, opts.LogLevel); err != nil {
+		log.WithError(err).Error("Error setting up logging")
+		return 1
+	}
+
 	var clientset *kubernetes.Clientset
 	{
-		// For now always use the built in service account.
-		config, err := rest.InClusterConfig()
+		config, err := kubeConfig(opts.Kubeconfig, opts.Context, opts.Master)
 		if err != nil {
-			log.Errorf("Error getting Kubernetes config: %v", err)
+			log.WithError(err).Error("Error getting Kubernetes config")
 			return 1
 		}
 
 		clientset, err = kubernetes.NewForConfig(config)
 		if err != nil {
-			log.Errorf("Error creating Kubernetes clientset: %v", err)
+			log.WithError(err).Error("Error creating Kubernetes clientset")
+			return 1
+		}
+	}
+
+	tprConfig := opts.tprConfig()
+	checkClient := tpr.NewClient(clientset, tprConfig)
+	to := tpr.New(tprConfig, clientset)
+	// Only Pingdom requests wait for the API quota.
+	pingdomHTTP := &http.Client{Transport: pingdom.NewQuotaTransport(nil)}
+	pclient := pingdom.NewReloadingChecks(opts.PingdomAPIVersion, pingdomHTTP)
+	switch {
+	case opts.CredentialsSecret != "":
+		// Loaded by the Secret watch.
+	case opts.CredentialsDir != "":
+		if err := pclient.LoadDir(opts.CredentialsDir); err != nil {
+			log.WithError(err).Error("Error loading Pingdom credentials")
+			return 1
+		}
+	default:
+		if err := pclient.SetCredentials(pingdom.CredentialsFromEnv()); err != nil {
+			log.WithError(err).Error("Error loading Pingdom credentials")
+			return 1
+		}
+	}
+	po := pingdom.New(opts.pingdomConfig(), clientset, checkClient, pclient)
+
+	var le *election.LeaderElector
+	if opts.LeaderElect {
+		// The hostname of a pod is its name.
+		identity, err := os.Hostname()
+		if err != nil {
+			log.WithError(err).Error("Error getting hostname")
 			return 1
 		}
+		le = election.New(clientset, election.Config{
+			Namespace:     opts.LeaseNamespace,
+			Name:          opts.LeaseName,
+			Identity:      identity,
+			LeaseDuration: election.DefaultLeaseDuration,
+			RenewDeadline: election.DefaultRenewDeadline,
+			RetryPeriod:   election.DefaultRetryPeriod,
+		})
 	}
 
-	tprStore := tpr.NewStore()
-	to := tpr.New(v1.NamespaceAll, clientset, tprStore)
-	po := pingdom.New(v1.NamespaceAll, clientset, tprStore)
+	go func() {
+		http.Handle("/metrics", promhttp.Handler())
+		http.Handle("/healthz", readyHandler(po.Healthy))
+		http.Handle("/readyz", readyHandler(to.Ready, pclient.Ready, po.Ready))
+		http.Handle("/log-level", logLevelHandler())
+		if err := http.ListenAndServe(opts.ListenAddress, nil); err != nil {
+			log.WithError(err).Error("Error serving HTTP")
+		}
+	}()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	wg, ctx := errgroup.WithContext(ctx)
 
 	wg.Go(func() error { return to.Run(ctx.Done()) })
-	wg.Go(func() error { return po.Run(ctx.Done()) })
+	wg.Go(func() error { return po.RunInformers(ctx.Done()) })
+
+	switch {
+	case opts.CredentialsSecret != "":
+		parts := strings.Split(opts.CredentialsSecret, "/")
+		wg.Go(func() error {
+			pclient.WatchSecret(clientset, parts[0], parts[1], ctx.Done())
+			return nil
+		})
+	case opts.CredentialsDir != "":
+		wg.Go(func() error {
+			pclient.WatchDir(opts.CredentialsDir, ctx.Done())
+			return nil
+		})
+	}
+
+	if le != nil {
+		wg.Go(func() error {
+			return le.Run(ctx.Done(), func(stopc <-chan struct{}) { po.Run(stopc) })
+		})
+	} else {
+		wg.Go(func() error { return po.Run(ctx.Done()) })
+	}
 
 	term := make(chan os.Signal)
 	signal.Notify(term, os.Interrupt, syscall.SIGTERM)
@@ -58,13 +141,46 @@ func Main() int {
 
 	cancel()
 	if err := wg.Wait(); err != nil {
-		log.Errorf("Unhanded error exiting: %v", err)
+		log.WithError(err).Error("Unhandled error exiting")
 		return 1
 	}
 
 	return 0
 }
 
+// kubeConfig returns the in-cluster config if no flag is set and the
+// operator runs in a pod, the config loaded from kubeconfig otherwise.
+func kubeConfig(kubeconfig, context, master string) (*rest.Config, error) {
+	if kubeconfig == "" && context == "" && master == "" {
+		config, err := rest.InClusterConfig()
+		if err == nil {
+			return config, nil
+		}
+		log.WithError(err).Info("Not running in a cluster, loading kubeconfig")
+	}
+
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	overrides := &clientcmd.ConfigOverrides{
+		CurrentContext: context,
+		ClusterInfo:    clientcmdapi.Cluster{Server: master},
+	}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+}
+
+// readyHandler responds with 503 and the error of the first failing check.
+func readyHandler(checks ...func() error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, check := range checks {
+			if err := check(); err != nil {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+		}
+		w.Write([]byte("ok"))
+	})
+}
+
 func main() {
 	os.Exit(Main())
 }
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/pkg/labels"
+
+	"github.com/rossf7/pingdom-operator/pkg/pingdom"
+	"github.com/rossf7/pingdom-operator/pkg/tpr"
+)
+
+// options holds the settings of the operator. They are read from the
+// optional YAML config file, using the flag names in camel case as keys,
+// and overridden by the flags set on the command line.
+type options struct {
+	ConfigFile string `json:"-"`
+
+	Kubeconfig string `json:"kubeconfig"`
+	Context    string `json:"context"`
+	Master     string `json:"master"`
+
+	Namespaces        stringList `json:"namespaces"`
+	NamespaceSelector string     `json:"namespaceSelector"`
+	ResyncPeriod      duration   `json:"resyncPeriod"`
+	LogLevel          string     `json:"logLevel"`
+	LogFormat         string     `json:"logFormat"`
+
+	CRDGroup   string `json:"crdGroup"`
+	CRDVersion string `json:"crdVersion"`
+	CreateCRD  bool   `json:"createCRD"`
+
+	Workers          int      `json:"workers"`
+	ClusterID        string   `json:"clusterID"`
+	GCPeriod         duration `json:"gcPeriod"`
+	GCDryRun         bool     `json:"gcDryRun"`
+	Annotation       string   `json:"annotation"`
+	ChecksAnnotation string   `json:"checksAnnotation"`
+	DryRun           bool     `json:"dryRun"`
+	DryRunAnnotation string   `json:"dryRunAnnotation"`
+	// Only set in the config file.
+	DefaultCheckSpec tpr.Spec `json:"defaultCheckSpec"`
+
+	ListenAddress     string   `json:"listenAddress"`
+	PingdomAPIVersion string   `json:"pingdomAPIVersion"`
+	CredentialsSecret string   `json:"credentialsSecret"`
+	CredentialsDir    string   `json:"credentialsDir"`
+	HeartbeatTimeout  duration `json:"heartbeatTimeout"`
+	APIReadyWindow    duration `json:"apiReadyWindow"`
+	APIQPS            float64  `json:"apiQPS"`
+	APIBurst          int      `json:"apiBurst"`
+	APIRetries        int      `json:"apiRetries"`
+	ListCacheTTL      duration `json:"listCacheTTL"`
+
+	LeaderElect    bool   `json:"leaderElect"`
+	LeaseNamespace string `json:"leaseNamespace"`
+	LeaseName      string `json:"leaseName"`
+}
+
+func defaultOptions() *options {
+	pc := pingdom.DefaultConfig()
+	tc := tpr.DefaultConfig()
+	return &options{
+		Namespaces:        pc.Namespaces,
+		NamespaceSelector: pc.NamespaceSelector,
+		ResyncPeriod:      duration{pc.ResyncPeriod},
+		LogLevel:          "info",
+		LogFormat:         logFormatText,
+		CRDGroup:          tc.Group,
+		CRDVersion:        tc.Version,
+		CreateCRD:         tc.CreateCRD,
+		Workers:           pc.Workers,
+		GCPeriod:          duration{pingdom.DefaultGCPeriod},
+		GCDryRun:          pc.GCDryRun,
+		Annotation:        pc.Annotation,
+		ChecksAnnotation:  pc.ChecksAnnotation,
+		DefaultCheckSpec:  pc.DefaultCheckSpec,
+		DryRun:            pc.DryRun,
+		DryRunAnnotation:  pc.DryRunAnnotation,
+		ListenAddress:     ":8080",
+		HeartbeatTimeout:  duration{pc.HeartbeatTimeout},
+		APIReadyWindow:    duration{pc.APIReadyWindow},
+		APIQPS:            float64(pc.APIQPS),
+		APIBurst:          pc.APIBurst,
+		APIRetries:        pc.APIRetries,
+		ListCacheTTL:      duration{pc.ListCacheTTL},
+		LeaderElect:       true,
+		LeaseNamespace:    "default",
+		LeaseName:         "pingdom-operator",
+	}
+}
+
+func (o *options) addFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.ConfigFile, "config", o.ConfigFile, "Path to a YAML config file. Flags override its settings.")
+
+	fs.StringVar(&o.Kubeconfig, "kubeconfig", o.Kubeconfig, "Path to a kubeconfig file. Defaults to the in-cluster config, $KUBECONFIG or ~/.kube/config.")
+	fs.StringVar(&o.Context, "context", o.Context, "Context of the kubeconfig to use instead of the current context.")
+	fs.StringVar(&o.Master, "master", o.Master, "Address of the Kubernetes API server, overrides the kubeconfig.")
+
+	fs.Var(&o.Namespaces, "namespaces", "Comma separated namespaces of the watched Ingresses and Checks. All namespaces are watched if empty and there is no namespace selector.")
+	fs.StringVar(&o.NamespaceSelector, "namespace-selector", o.NamespaceSelector, "Label selector of the watched namespaces, e.g. \"pingdom=enabled\".")
+	fs.Var(&o.ResyncPeriod, "resync-period", "Interval of reconciling all Ingresses.")
+	fs.StringVar(&o.LogLevel, "log-level", o.LogLevel, "Log level: error, warning, info or debug. It can be changed at runtime at /log-level.")
+	fs.StringVar(&o.LogFormat, "log-format", o.LogFormat, "Log format: text or json.")
+
+	fs.StringVar(&o.CRDGroup, "crd-group", o.CRDGroup, "API group of the Check resource.")
+	fs.StringVar(&o.CRDVersion, "crd-version", o.CRDVersion, "API version of the Check resource.")
+	fs.BoolVar(&o.CreateCRD, "create-crd", o.CreateCRD, "Register the Check CRD. Disable it if the operator has no cluster-wide permissions and the CRD is installed by an admin.")
+
+	fs.IntVar(&o.Workers, "workers", o.Workers, "Number of workers processing Ingress and Check events.")
+	fs.StringVar(&o.ClusterID, "cluster-id", o.ClusterID, "Cluster ID added to the names of created checks. Must be unique per Pingdom account. Garbage collection is disabled unless it is set.")
+	fs.Var(&o.GCPeriod, "gc-period", "Interval of deleting checks whose Ingress no longer exists, once --cluster-id is set. 0 disables it.")
+	fs.BoolVar(&o.GCDryRun, "gc-dry-run", o.GCDryRun, "Only log the checks garbage collection would delete.")
+	fs.StringVar(&o.Annotation, "annotation", o.Annotation, "Annotation of Ingresses naming the Check resource.")
+	fs.StringVar(&o.ChecksAnnotation, "checks-annotation", o.ChecksAnnotation, "Annotation of Ingresses listing the created checks.")
+	fs.BoolVar(&o.DryRun, "dry-run", o.DryRun, "Only log the Pingdom checks which would be created, updated and deleted.")
+	fs.StringVar(&o.DryRunAnnotation, "dry-run-annotation", o.DryRunAnnotation, "Annotation of Ingresses listing the checks planned in dry-run mode. Ingresses are not annotated if empty.")
+
+	fs.StringVar(&o.ListenAddress, "listen-address", o.ListenAddress, "Address of the HTTP server serving /metrics, /healthz, /readyz and /log-level.")
+	fs.StringVar(&o.PingdomAPIVersion, "pingdom-api-version", o.PingdomAPIVersion, "Pingdom API version: 3.1, authenticating with $PINGDOM_API_TOKEN, or the legacy 2.0, authenticating with $PINGDOM_USER, $PINGDOM_PASSWORD and $PINGDOM_API_KEY. Defaults to 2.0 if only the 2.0 credentials are set, and 3.1 otherwise.")
+	fs.StringVar(&o.CredentialsSecret, "credentials-secret", o.CredentialsSecret, "Secret with the Pingdom credentials as namespace/name, with the keys api-token, or api-user, api-password and api-key for the 2.0 API. Changes are applied without restarting.")
+	fs.StringVar(&o.CredentialsDir, "credentials-dir", o.CredentialsDir, "Directory of a mounted Secret with the Pingdom credentials, read periodically. The credentials are read from the environment if neither this nor --credentials-secret is set.")
+	fs.Var(&o.HeartbeatTimeout, "heartbeat-timeout", "How long the workers may not process any Ingress or Check before /healthz fails. 0 disables it.")
+	fs.Var(&o.APIReadyWindow, "api-ready-window", "How long the result of the last Pingdom API call is used by /readyz before calling the API again.")
+	fs.Float64Var(&o.APIQPS, "api-qps", o.APIQPS, "Pingdom API requests per second. 0 disables the limit.")
+	fs.IntVar(&o.APIBurst, "api-burst", o.APIBurst, "Burst of Pingdom API requests above --api-qps.")
+	fs.IntVar(&o.APIRetries, "api-retries", o.APIRetries, "Retries of Pingdom API requests failing with 429 or 5xx responses, with exponential backoff.")
+	fs.Var(&o.ListCacheTTL, "list-cache-ttl", "How long the list of Pingdom checks is reused by reconciles before listing them again. 0 disables the cache.")
+
+	fs.BoolVar(&o.LeaderElect, "leader-elect", o.LeaderElect, "Elect a leader among the operator replicas. Only the leader manages Pingdom checks.")
+	fs.StringVar(&o.LeaseNamespace, "lease-namespace", o.LeaseNamespace, "Namespace of the leader election lease ConfigMap.")
+	fs.StringVar(&o.LeaseName, "lease-name", o.LeaseName, "Name of the leader election lease ConfigMap.")
+}
+
+// parse parses the flags and the config file they point to. The flags are
+// parsed again after reading the config file so they take precedence.
+func (o *options) parse(fs *flag.FlagSet, args []string) error {
+	o.addFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if o.ConfigFile != "" {
+		data, err := ioutil.ReadFile(o.ConfigFile)
+		if err != nil {
+			return fmt.Errorf("reading config file: %v", err)
+		}
+		if err := yaml.Unmarshal(data, o); err != nil {
+			return fmt.Errorf("parsing config file %s: %v", o.ConfigFile, err)
+		}
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+	}
+
+	return o.validate()
+}
+
+func (o *options) validate() error {
+	if _, err := logrus.ParseLevel(o.LogLevel); err != nil {
+		return fmt.Errorf("invalid log level %q", o.LogLevel)
+	}
+	if o.LogFormat != logFormatText && o.LogFormat != logFormatJSON {
+		return fmt.Errorf("invalid log format %q", o.LogFormat)
+	}
+	if len(o.Namespaces) > 0 && o.NamespaceSelector != "" {
+		return fmt.Errorf("namespaces and namespace selector are mutually exclusive")
+	}
+	if _, err := labels.Parse(o.NamespaceSelector); err != nil {
+		return fmt.Errorf("invalid namespace selector: %v", err)
+	}
+	if o.PingdomAPIVersion != "" && o.PingdomAPIVersion != pingdom.APIVersion2 && o.PingdomAPIVersion != pingdom.APIVersion31 {
+		return fmt.Errorf("invalid pingdom api version %q", o.PingdomAPIVersion)
+	}
+	if o.CredentialsSecret != "" && o.CredentialsDir != "" {
+		return fmt.Errorf("credentials secret and credentials dir are mutually exclusive")
+	}
+	if o.CredentialsSecret != "" {
+		if parts := strings.Split(o.CredentialsSecret, "/"); len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("invalid credentials secret %q, must be namespace/name", o.CredentialsSecret)
+		}
+	}
+	if o.APIQPS < 0 || o.APIRetries < 0 || o.ListCacheTTL.Duration < 0 {
+		return fmt.Errorf("api qps, retries and list cache ttl must not be negative")
+	}
+	if o.APIQPS > 0 && o.APIBurst < 1 {
+		return fmt.Errorf("api burst must be at least 1")
+	}
+	// Checks of other clusters using the default ID would be deleted.
+	if o.ClusterID == pingdom.DefaultClusterID && o.GCPeriod.Duration > 0 && !o.GCDryRun {
+		return fmt.Errorf("garbage collection needs a cluster id other than %q, or --gc-dry-run", pingdom.DefaultClusterID)
+	}
+	if o.Workers < 1 {
+		return fmt.Errorf("workers must be at least 1")
+	}
+	if o.Annotation == "" || o.ChecksAnnotation == "" {
+		return fmt.Errorf("annotation keys must not be empty")
+	}
+	if o.Annotation == o.ChecksAnnotation {
+		return fmt.Errorf("annotation and checks annotation must differ")
+	}
+	if o.DryRunAnnotation == o.Annotation || o.DryRunAnnotation == o.ChecksAnnotation {
+		return fmt.Errorf("dry-run annotation must differ from the other annotations")
+	}
+	if err := o.DefaultCheckSpec.Validate(); err != nil {
+		return fmt.Errorf("invalid default check spec: %v", err)
+	}
+	return nil
+}
+
+func (o *options) tprConfig() tpr.Config {
+	return tpr.Config{
+		Group:     o.CRDGroup,
+		Version:   o.CRDVersion,
+		CreateCRD: o.CreateCRD,
+	}
+}
+
+func (o *options) pingdomConfig() pingdom.Config {
+	// Garbage collection only runs once a cluster ID is set.
+	clusterID, gcPeriod := o.ClusterID, o.GCPeriod.Duration
+	if clusterID == "" {
+		clusterID, gcPeriod = pingdom.DefaultClusterID, 0
+	}
+	return pingdom.Config{
+		Namespaces:        o.Namespaces,
+		NamespaceSelector: o.NamespaceSelector,
+		Workers:           o.Workers,
+		ClusterID:         clusterID,
+		GCPeriod:          gcPeriod,
+		GCDryRun:          o.GCDryRun,
+		HeartbeatTimeout:  o.HeartbeatTimeout.Duration,
+		APIReadyWindow:    o.APIReadyWindow.Duration,
+		APIQPS:            float32(o.APIQPS),
+		APIBurst:          o.APIBurst,
+		APIRetries:        o.APIRetries,
+		ListCacheTTL:      o.ListCacheTTL.Duration,
+		ResyncPeriod:      o.ResyncPeriod.Duration,
+		Annotation:        o.Annotation,
+		ChecksAnnotation:  o.ChecksAnnotation,
+		DefaultCheckSpec:  o.DefaultCheckSpec,
+		DryRun:            o.DryRun,
+		DryRunAnnotation:  o.DryRunAnnotation,
+	}
+}
+
+// duration is a time.Duration set by flags and read from the config file
+// as a string like "5m".
+type duration struct {
+	time.Duration
+}
+
+func (d *duration) Set(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"5m\": %s", data)
+	}
+	return d.Set(s)
+}
+
+// stringList is a list set by a comma separated flag and read from the
+// config file as a list.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = nil
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
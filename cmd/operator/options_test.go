@@ -0,0 +1,163 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfig(t *testing.T, data string) string {
+	f, err := ioutil.TempFile("", "pingdom-operator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+func TestOptionsDefaults(t *testing.T) {
+	opts := defaultOptions()
+	err := opts.parse(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	assert.Nil(t, err)
+
+	pc := opts.pingdomConfig()
+	assert.Nil(t, pc.Namespaces)
+	assert.Equal(t, "", pc.NamespaceSelector)
+	assert.Equal(t, 2, pc.Workers)
+	// Garbage collection is disabled without a cluster ID.
+	assert.Equal(t, "default", pc.ClusterID)
+	assert.Equal(t, time.Duration(0), pc.GCPeriod)
+	assert.Equal(t, "monitoring.rossfairbanks.com/pingdom", pc.Annotation)
+	assert.Equal(t, 1, pc.DefaultCheckSpec.Resolution)
+	// Picked by the credentials.
+	assert.Equal(t, "", opts.PingdomAPIVersion)
+
+	tc := opts.tprConfig()
+	assert.Equal(t, "pingdom.example.com", tc.Group)
+	assert.Equal(t, "v1alpha1", tc.Version)
+	assert.True(t, tc.CreateCRD)
+}
+
+func TestOptionsConfigFile(t *testing.T) {
+	path := writeConfig(t, `
+namespaces: [monitoring, web]
+resyncPeriod: 10m
+workers: 4
+gcPeriod: 30m
+clusterID: prod
+annotation: example.com/pingdom
+crdGroup: pingdom.example.org
+defaultCheckSpec:
+  resolution: 5
+  contactIds: [1, 2]
+`)
+	defer os.Remove(path)
+
+	opts := defaultOptions()
+	err := opts.parse(flag.NewFlagSet("test", flag.ContinueOnError), []string{
+		"--config", path,
+		"--workers", "8",
+	})
+	assert.Nil(t, err)
+
+	pc := opts.pingdomConfig()
+	assert.Equal(t, []string{"monitoring", "web"}, pc.Namespaces)
+	assert.Equal(t, 10*time.Minute, pc.ResyncPeriod)
+	// Flags override the config file.
+	assert.Equal(t, 8, pc.Workers)
+	assert.Equal(t, 30*time.Minute, pc.GCPeriod)
+	assert.Equal(t, "example.com/pingdom", pc.Annotation)
+	assert.Equal(t, "monitoring.rossfairbanks.com/pingdom_checks", pc.ChecksAnnotation)
+	assert.Equal(t, 5, pc.DefaultCheckSpec.Resolution)
+	assert.Equal(t, []int{1, 2}, pc.DefaultCheckSpec.ContactIDs)
+
+	tc := opts.tprConfig()
+	assert.Equal(t, "pingdom.example.org", tc.Group)
+	assert.Equal(t, "v1alpha1", tc.Version)
+}
+
+func TestOptionsNamespaces(t *testing.T) {
+	opts := defaultOptions()
+	err := opts.parse(flag.NewFlagSet("test", flag.ContinueOnError), []string{
+		"--namespaces", "team-a, team-b",
+		"--create-crd=false",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"team-a", "team-b"}, opts.pingdomConfig().Namespaces)
+	assert.False(t, opts.tprConfig().CreateCRD)
+
+	opts = defaultOptions()
+	err = opts.parse(flag.NewFlagSet("test", flag.ContinueOnError), []string{
+		"--namespace-selector", "pingdom in (enabled)",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "pingdom in (enabled)", opts.pingdomConfig().NamespaceSelector)
+}
+
+func TestOptionsInvalid(t *testing.T) {
+	for _, args := range [][]string{
+		{"--log-level", "LOUD"},
+		{"--log-format", "xml"},
+		{"--workers", "0"},
+		{"--api-burst", "0"},
+		{"--api-retries", "-1"},
+		{"--list-cache-ttl", "-1m"},
+		{"--pingdom-api-version", "2.1"},
+		{"--credentials-secret", "pingdom-secret"},
+		{"--credentials-secret", "monitoring/pingdom-secret", "--credentials-dir", "/etc/pingdom"},
+		{"--checks-annotation", "monitoring.rossfairbanks.com/pingdom"},
+		{"--gc-period", "often"},
+		{"--cluster-id", "default"},
+		{"--cluster-id", "default", "--gc-period", "30m"},
+		{"--namespaces", "team-a", "--namespace-selector", "pingdom=enabled"},
+		{"--namespace-selector", "pingdom in enabled"},
+		{"--dry-run-annotation", "monitoring.rossfairbanks.com/pingdom_checks"},
+	} {
+		opts := defaultOptions()
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(ioutil.Discard)
+		assert.NotNil(t, opts.parse(fs, args), "%v", args)
+	}
+
+	path := writeConfig(t, "gcPeriod: 3600\n")
+	defer os.Remove(path)
+	opts := defaultOptions()
+	assert.NotNil(t, opts.parse(flag.NewFlagSet("test", flag.ContinueOnError), []string{"--config", path}))
+}
+
+func TestOptionsDryRun(t *testing.T) {
+	opts := defaultOptions()
+	err := opts.parse(flag.NewFlagSet("test", flag.ContinueOnError), []string{
+		"--dry-run",
+		"--dry-run-annotation", "",
+	})
+	assert.Nil(t, err)
+
+	pc := opts.pingdomConfig()
+	assert.True(t, pc.DryRun)
+	assert.Equal(t, "", pc.DryRunAnnotation)
+}
+
+func TestOptionsGarbageCollection(t *testing.T) {
+	opts := defaultOptions()
+	err := opts.parse(flag.NewFlagSet("test", flag.ContinueOnError), []string{"--cluster-id", "prod"})
+	assert.Nil(t, err)
+	pc := opts.pingdomConfig()
+	assert.Equal(t, "prod", pc.ClusterID)
+	assert.Equal(t, time.Hour, pc.GCPeriod)
+
+	// The default cluster ID may be shared, so only a dry run is allowed.
+	opts = defaultOptions()
+	err = opts.parse(flag.NewFlagSet("test", flag.ContinueOnError), []string{"--cluster-id", "default", "--gc-dry-run"})
+	assert.Nil(t, err)
+	pc = opts.pingdomConfig()
+	assert.True(t, pc.GCDryRun)
+	assert.Equal(t, time.Hour, pc.GCPeriod)
+}
@@ -0,0 +1,276 @@
+// Package election implements leader election with a lease stored in a
+// ConfigMap annotation, so only one replica of the operator manages
+// Pingdom checks at a time.
+package election
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/client-go/kubernetes"
+	apierrors "k8s.io/client-go/pkg/api/errors"
+	"k8s.io/client-go/pkg/api/unversioned"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+const (
+	// LeaderAnnotation is the ConfigMap annotation holding the lease.
+	LeaderAnnotation = "control-plane.alpha.kubernetes.io/leader"
+
+	DefaultLeaseDuration = 15 * time.Second
+	DefaultRenewDeadline = 10 * time.Second
+	DefaultRetryPeriod   = 2 * time.Second
+)
+
+var (
+	log = logrus.WithField("component", "election")
+
+	// ErrLeaderLost is returned by Run when the lease could not be renewed.
+	ErrLeaderLost = errors.New("leader election lost")
+)
+
+type Config struct {
+	// Namespace and name of the lease ConfigMap.
+	Namespace string
+	Name      string
+	// Identity of this replica, e.g. the pod name.
+	Identity string
+
+	// Duration followers wait before taking over a lease which was not
+	// renewed.
+	LeaseDuration time.Duration
+	// Duration the leader retries renewing the lease before giving up.
+	RenewDeadline time.Duration
+	// Interval of acquiring and renewing the lease.
+	RetryPeriod time.Duration
+}
+
+// LeaderRecord is stored in the lease annotation.
+type LeaderRecord struct {
+	HolderIdentity       string           `json:"holderIdentity"`
+	LeaseDurationSeconds int              `json:"leaseDurationSeconds"`
+	AcquireTime          unversioned.Time `json:"acquireTime"`
+	RenewTime            unversioned.Time `json:"renewTime"`
+	LeaderTransitions    int              `json:"leaderTransitions"`
+}
+
+type LeaderElector struct {
+	client kubernetes.Interface
+	config Config
+	now    func() time.Time
+
+	// Last record seen and when it was seen, using the local clock as
+	// clocks of the replicas may be skewed.
+	observedRecord LeaderRecord
+	observedTime   time.Time
+}
+
+func New(client kubernetes.Interface, config Config) *LeaderElector {
+	return &LeaderElector{
+		client: client,
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// Run blocks until the lease is acquired and then calls run with a channel
+// closed when the leadership ends. It returns nil when stopc is closed, and
+// ErrLeaderLost if the lease could not be renewed in time. The lease is
+// released once run returned, so run must only return when it stopped
+// acting as the leader, and another replica can then take over
+// immediately.
+func (le *LeaderElector) Run(stopc <-chan struct{}, run func(stopc <-chan struct{})) error {
+	if !le.acquire(stopc) {
+		return nil
+	}
+
+	leadc := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		run(leadc)
+	}()
+
+	err := le.renew(stopc)
+	close(leadc)
+	<-done
+	le.release()
+	return err
+}
+
+func (le *LeaderElector) acquire(stopc <-chan struct{}) bool {
+	le.logger().Info("Acquiring lease")
+	tick := time.NewTicker(le.config.RetryPeriod)
+	defer tick.Stop()
+
+	for {
+		ok, err := le.tryAcquireOrRenew()
+		if err != nil {
+			le.logger().WithError(err).Error("Error acquiring lease")
+		}
+		if ok {
+			le.logger().Info("Acquired lease")
+			return true
+		}
+		select {
+		case <-tick.C:
+		case <-stopc:
+			return false
+		}
+	}
+}
+
+func (le *LeaderElector) renew(stopc <-chan struct{}) error {
+	tick := time.NewTicker(le.config.RetryPeriod)
+	defer tick.Stop()
+
+	lastRenew := le.now()
+	for {
+		select {
+		case <-tick.C:
+		case <-stopc:
+			return nil
+		}
+
+		ok, err := le.tryAcquireOrRenew()
+		if err != nil {
+			le.logger().WithError(err).Warning("Error renewing lease")
+		}
+		if ok {
+			lastRenew = le.now()
+			continue
+		}
+		if err == nil || le.now().Sub(lastRenew) > le.config.RenewDeadline {
+			le.logger().Error("Lost lease")
+			return ErrLeaderLost
+		}
+	}
+}
+
+// tryAcquireOrRenew creates or updates the lease if it is held by this
+// replica, not held or expired. It returns false without an error if the
+// lease is held by another replica.
+func (le *LeaderElector) tryAcquireOrRenew() (bool, error) {
+	now := unversioned.NewTime(le.now())
+	record := LeaderRecord{
+		HolderIdentity:       le.config.Identity,
+		LeaseDurationSeconds: int(le.config.LeaseDuration / time.Second),
+		AcquireTime:          now,
+		RenewTime:            now,
+	}
+
+	configMaps := le.client.CoreV1().ConfigMaps(le.config.Namespace)
+	cm, err := configMaps.Get(le.config.Name)
+	if apierrors.IsNotFound(err) {
+		cm = &v1.ConfigMap{}
+		cm.Namespace = le.config.Namespace
+		cm.Name = le.config.Name
+		if err := setRecord(cm, record); err != nil {
+			return false, err
+		}
+		if _, err := configMaps.Create(cm); err != nil {
+			return false, err
+		}
+		le.observe(record)
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	old, err := getRecord(cm)
+	if err != nil {
+		return false, err
+	}
+	if old.HolderIdentity != le.observedRecord.HolderIdentity || !old.RenewTime.Equal(le.observedRecord.RenewTime) {
+		le.observe(old)
+	}
+
+	held := old.HolderIdentity != "" && old.HolderIdentity != le.config.Identity
+	if held && le.observedTime.Add(le.config.LeaseDuration).After(le.now()) {
+		return false, nil
+	}
+
+	if old.HolderIdentity == le.config.Identity {
+		record.AcquireTime = old.AcquireTime
+		record.LeaderTransitions = old.LeaderTransitions
+	} else {
+		record.LeaderTransitions = old.LeaderTransitions + 1
+	}
+
+	if err := setRecord(cm, record); err != nil {
+		return false, err
+	}
+	// Updates fail with a conflict if another replica updated the lease
+	// since it was read.
+	if _, err := configMaps.Update(cm); err != nil {
+		return false, err
+	}
+	le.observe(record)
+	return true, nil
+}
+
+// release clears the holder of the lease if it is still held by this
+// replica.
+func (le *LeaderElector) release() {
+	configMaps := le.client.CoreV1().ConfigMaps(le.config.Namespace)
+	cm, err := configMaps.Get(le.config.Name)
+	if err != nil {
+		le.logger().WithError(err).Warning("Error releasing lease")
+		return
+	}
+	record, err := getRecord(cm)
+	if err != nil || record.HolderIdentity != le.config.Identity {
+		return
+	}
+
+	record.HolderIdentity = ""
+	if err := setRecord(cm, record); err != nil {
+		return
+	}
+	if _, err := configMaps.Update(cm); err != nil {
+		le.logger().WithError(err).Warning("Error releasing lease")
+		return
+	}
+	le.logger().Info("Released lease")
+}
+
+func (le *LeaderElector) logger() *logrus.Entry {
+	return log.WithFields(logrus.Fields{
+		"lease":    le.config.Namespace + "/" + le.config.Name,
+		"identity": le.config.Identity,
+	})
+}
+
+func (le *LeaderElector) observe(record LeaderRecord) {
+	le.observedRecord = record
+	le.observedTime = le.now()
+}
+
+func getRecord(cm *v1.ConfigMap) (LeaderRecord, error) {
+	var record LeaderRecord
+	v, ok := cm.Annotations[LeaderAnnotation]
+	if !ok {
+		return record, nil
+	}
+	if err := json.Unmarshal([]byte(v), &record); err != nil {
+		return record, fmt.Errorf("parsing %s annotation: %v", LeaderAnnotation, err)
+	}
+	return record, nil
+}
+
+func setRecord(cm *v1.ConfigMap, record LeaderRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if cm.Annotations == nil {
+		cm.Annotations = make(map[string]string)
+	}
+	cm.Annotations[LeaderAnnotation] = string(data)
+	return nil
+}
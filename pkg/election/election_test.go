@@ -0,0 +1,195 @@
+package election
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time          { return c.t }
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newElector(client kubernetes.Interface, c *clock, identity string) *LeaderElector {
+	le := New(client, Config{
+		Namespace:     "default",
+		Name:          "pingdom-operator",
+		Identity:      identity,
+		LeaseDuration: DefaultLeaseDuration,
+		RenewDeadline: DefaultRenewDeadline,
+		RetryPeriod:   DefaultRetryPeriod,
+	})
+	le.now = c.now
+	return le
+}
+
+func leaderRecord(t *testing.T, client kubernetes.Interface) LeaderRecord {
+	cm, err := client.CoreV1().ConfigMaps("default").Get("pingdom-operator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := getRecord(cm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return record
+}
+
+func TestAcquireExpiredLease(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	c := &clock{t: time.Unix(1000, 0)}
+	a := newElector(client, c, "a")
+	b := newElector(client, c, "b")
+
+	ok, err := a.tryAcquireOrRenew()
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "a", leaderRecord(t, client).HolderIdentity)
+
+	ok, err = b.tryAcquireOrRenew()
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	// b only takes over after observing the same record for the lease
+	// duration.
+	c.advance(DefaultLeaseDuration - time.Second)
+	ok, err = b.tryAcquireOrRenew()
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	c.advance(2 * time.Second)
+	ok, err = b.tryAcquireOrRenew()
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	record := leaderRecord(t, client)
+	assert.Equal(t, "b", record.HolderIdentity)
+	assert.Equal(t, 1, record.LeaderTransitions)
+	assert.Equal(t, 15, record.LeaseDurationSeconds)
+}
+
+func TestRenewLease(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	c := &clock{t: time.Unix(1000, 0)}
+	a := newElector(client, c, "a")
+	b := newElector(client, c, "b")
+
+	ok, _ := a.tryAcquireOrRenew()
+	assert.True(t, ok)
+	acquired := leaderRecord(t, client).AcquireTime
+
+	for i := 0; i < 10; i++ {
+		c.advance(DefaultRetryPeriod)
+		ok, err := a.tryAcquireOrRenew()
+		assert.Nil(t, err)
+		assert.True(t, ok)
+
+		ok, err = b.tryAcquireOrRenew()
+		assert.Nil(t, err)
+		assert.False(t, ok)
+	}
+
+	record := leaderRecord(t, client)
+	assert.Equal(t, "a", record.HolderIdentity)
+	assert.True(t, acquired.Equal(record.AcquireTime))
+	assert.Equal(t, 0, record.LeaderTransitions)
+}
+
+func TestReleaseLease(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	c := &clock{t: time.Unix(1000, 0)}
+	a := newElector(client, c, "a")
+	b := newElector(client, c, "b")
+
+	ok, _ := a.tryAcquireOrRenew()
+	assert.True(t, ok)
+
+	a.release()
+	assert.Equal(t, "", leaderRecord(t, client).HolderIdentity)
+
+	ok, err := b.tryAcquireOrRenew()
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "b", leaderRecord(t, client).HolderIdentity)
+
+	// Releasing a lease held by another replica does nothing.
+	a.release()
+	assert.Equal(t, "b", leaderRecord(t, client).HolderIdentity)
+}
+
+func TestRun(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	le := New(client, Config{
+		Namespace:     "default",
+		Name:          "pingdom-operator",
+		Identity:      "a",
+		LeaseDuration: time.Second,
+		RenewDeadline: 500 * time.Millisecond,
+		RetryPeriod:   10 * time.Millisecond,
+	})
+
+	stopc := make(chan struct{})
+	started := make(chan struct{})
+	errc := make(chan error)
+	go func() {
+		errc <- le.Run(stopc, func(leadc <-chan struct{}) {
+			close(started)
+			<-leadc
+		})
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("leader not elected")
+	}
+
+	close(stopc)
+	assert.Nil(t, <-errc)
+	assert.Equal(t, "", leaderRecord(t, client).HolderIdentity)
+}
+
+func TestRunReleasesAfterStopped(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	le := New(client, Config{
+		Namespace:     "default",
+		Name:          "pingdom-operator",
+		Identity:      "a",
+		LeaseDuration: time.Second,
+		RenewDeadline: 500 * time.Millisecond,
+		RetryPeriod:   10 * time.Millisecond,
+	})
+
+	stopc := make(chan struct{})
+	started := make(chan struct{})
+	errc := make(chan error)
+	var holder string
+	var stopped bool
+	go func() {
+		errc <- le.Run(stopc, func(leadc <-chan struct{}) {
+			close(started)
+			<-leadc
+			// Still the leader while stopping, e.g. finishing a sync.
+			time.Sleep(50 * time.Millisecond)
+			holder = leaderRecord(t, client).HolderIdentity
+			stopped = true
+		})
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("leader not elected")
+	}
+
+	close(stopc)
+	assert.Nil(t, <-errc)
+	assert.True(t, stopped)
+	assert.Equal(t, "a", holder)
+	assert.Equal(t, "", leaderRecord(t, client).HolderIdentity)
+}
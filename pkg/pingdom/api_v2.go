@@ -0,0 +1,131 @@
+package pingdom
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	pdom "github.com/russellcardullo/go-pingdom/pingdom"
+)
+
+const defaultAPIV2URL = "https://api.pingdom.com/api/2.0"
+
+// checksV2 calls the checks endpoints of the legacy Pingdom 2.0 API like the
+// CheckService of go-pingdom, which always sends requests with
+// http.DefaultClient. It authenticates with basic auth and an application
+// key and takes form encoded parameters.
+type checksV2 struct {
+	baseURL  string
+	user     string
+	password string
+	appKey   string
+	client   *http.Client
+}
+
+// NewChecksV2 returns the checks API of Pingdom 2.0 authenticating with the
+// user, password and application key. Requests are sent with
+// http.DefaultClient if client is nil.
+func NewChecksV2(user, password, appKey string, client *http.Client) ChecksAPI {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &checksV2{baseURL: defaultAPIV2URL, user: user, password: password, appKey: appKey, client: client}
+}
+
+func (c *checksV2) List(params ...map[string]string) ([]pdom.CheckResponse, error) {
+	var body struct {
+		Checks []v31Check `json:"checks"`
+	}
+	if err := c.do(http.MethodGet, "/checks", formValues(params...), &body); err != nil {
+		return nil, err
+	}
+
+	list := make([]pdom.CheckResponse, len(body.Checks))
+	for i := range body.Checks {
+		list[i] = body.Checks[i].response()
+	}
+	return list, nil
+}
+
+func (c *checksV2) Create(check pdom.Check) (*pdom.CheckResponse, error) {
+	if err := check.Valid(); err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		Check v31Check `json:"check"`
+	}
+	if err := c.do(http.MethodPost, "/checks", formValues(check.PostParams()), &body); err != nil {
+		return nil, err
+	}
+	r := body.Check.response()
+	return &r, nil
+}
+
+func (c *checksV2) Read(id int) (*pdom.CheckResponse, error) {
+	var body struct {
+		Check v31Check `json:"check"`
+	}
+	if err := c.do(http.MethodGet, checkPath(id), nil, &body); err != nil {
+		return nil, err
+	}
+	r := body.Check.response()
+	return &r, nil
+}
+
+func (c *checksV2) Update(id int, check pdom.Check) (*pdom.PingdomResponse, error) {
+	if err := check.Valid(); err != nil {
+		return nil, err
+	}
+
+	resp := &pdom.PingdomResponse{}
+	if err := c.do(http.MethodPut, checkPath(id), formValues(check.PutParams()), resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *checksV2) Delete(id int) (*pdom.PingdomResponse, error) {
+	resp := &pdom.PingdomResponse{}
+	if err := c.do(http.MethodDelete, checkPath(id), nil, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// do sends the request with the parameters in the query of GET and DELETE
+// requests and in the form body otherwise, and decodes the response into
+// out.
+func (c *checksV2) do(method, path string, params url.Values, out interface{}) error {
+	u := c.baseURL + path
+	var form string
+	if method == http.MethodGet || method == http.MethodDelete {
+		if len(params) > 0 {
+			u += "?" + params.Encode()
+		}
+	} else {
+		form = params.Encode()
+	}
+
+	req, err := http.NewRequest(method, u, strings.NewReader(form))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.user, c.password)
+	req.Header.Set("App-Key", c.appKey)
+	req.Header.Set("Accept", "application/json")
+	if form != "" {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	return doRequest(c.client, req, out)
+}
+
+func formValues(params ...map[string]string) url.Values {
+	values := url.Values{}
+	for _, p := range params {
+		for k, v := range p {
+			values.Set(k, v)
+		}
+	}
+	return values
+}
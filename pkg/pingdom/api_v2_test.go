@@ -0,0 +1,73 @@
+package pingdom
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/rossf7/pingdom-operator/pkg/tpr"
+	pdom "github.com/russellcardullo/go-pingdom/pingdom"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecksV2(t *testing.T) {
+	responses := map[string]string{
+		"GET /checks":      `{"checks":[{"id":1,"name":"[default/default/pets] cat.example.com","hostname":"cat.example.com","resolution":1,"status":"up","type":"tcp"}]}`,
+		"POST /checks":     `{"check":{"id":2,"name":"[default/default/pets] dog.example.com"}}`,
+		"PUT /checks/1":    `{"message":"Modification of check was successful!"}`,
+		"DELETE /checks/1": `{"message":"Deletion of check was successful!"}`,
+	}
+	type call struct {
+		method string
+		path   string
+		form   url.Values
+	}
+	var calls []call
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ := r.BasicAuth()
+		assert.Equal(t, "user:pass", user+":"+password)
+		assert.Equal(t, "key", r.Header.Get("App-Key"))
+		assert.Nil(t, r.ParseForm())
+		calls = append(calls, call{method: r.Method, path: r.URL.Path, form: r.PostForm})
+
+		resp, ok := responses[r.Method+" "+r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"error":{"statuscode":404,"statusdesc":"Not Found","errormessage":"Check not found"}}`)
+			return
+		}
+		io.WriteString(w, resp)
+	}))
+	defer srv.Close()
+
+	c := NewChecksV2("user", "pass", "key", nil).(*checksV2)
+	c.baseURL = srv.URL
+
+	list, err := c.List()
+	assert.Nil(t, err)
+	assert.Len(t, list, 1)
+	assert.Equal(t, tpr.CheckTypeTCP, list[0].Type.Name)
+
+	check := &tcpCheck{baseCheck: baseCheck{Name: "[default/default/pets] dog.example.com", Hostname: "dog.example.com", Resolution: 5}, Port: 22}
+	r, err := c.Create(check)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, r.ID)
+
+	_, err = c.Update(1, check)
+	assert.Nil(t, err)
+	_, err = c.Delete(1)
+	assert.Nil(t, err)
+
+	_, err = c.Read(42)
+	assert.EqualError(t, err, "404 Not Found: Check not found")
+	_, ok := err.(*pdom.PingdomError)
+	assert.True(t, ok)
+
+	assert.Len(t, calls, 5)
+	assert.Equal(t, "tcp", calls[1].form.Get("type"))
+	assert.Equal(t, "22", calls[1].form.Get("port"))
+	assert.Equal(t, "", calls[2].form.Get("type"), "the type can't be changed")
+	assert.Equal(t, "5", calls[2].form.Get("resolution"))
+}
@@ -0,0 +1,319 @@
+package pingdom
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/rossf7/pingdom-operator/pkg/tpr"
+	pdom "github.com/russellcardullo/go-pingdom/pingdom"
+)
+
+// Versions of the Pingdom API. 2.0 is the legacy API authenticating with a
+// user, password and application key. An empty version picks the version
+// of the credentials, see NewChecksAPI.
+const (
+	APIVersion2  = "2.0"
+	APIVersion31 = "3.1"
+)
+
+const defaultAPIV31URL = "https://api.pingdom.com/api/3.1"
+
+// checksV31 calls the checks endpoints of the Pingdom 3.1 API, which
+// authenticates with a bearer token and takes JSON request bodies.
+// go-pingdom only supports the 2.0 API.
+type checksV31 struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// NewChecksV31 returns the checks API of Pingdom 3.1 authenticating with
+// the API token. Requests are sent with http.DefaultClient if client is nil.
+func NewChecksV31(token string, client *http.Client) ChecksAPI {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &checksV31{baseURL: defaultAPIV31URL, token: token, client: client}
+}
+
+// v31Check is a check of 3.1 responses, which are the same in 2.0. The type
+// is a string in lists and an object keyed by the type with its settings in
+// check details.
+type v31Check struct {
+	ID                       int             `json:"id"`
+	Name                     string          `json:"name"`
+	Hostname                 string          `json:"hostname"`
+	Resolution               int             `json:"resolution"`
+	Status                   string          `json:"status"`
+	Type                     json.RawMessage `json:"type"`
+	Created                  int64           `json:"created"`
+	LastErrorTime            int64           `json:"lasterrortime"`
+	LastTestTime             int64           `json:"lasttesttime"`
+	LastResponseTime         int64           `json:"lastresponsetime"`
+	SendNotificationWhenDown int             `json:"sendnotificationwhendown"`
+	NotifyAgainEvery         int             `json:"notifyagainevery"`
+	IntegrationIds           []int           `json:"integrationids"`
+}
+
+func (c *v31Check) response() pdom.CheckResponse {
+	r := pdom.CheckResponse{
+		ID:                       c.ID,
+		Name:                     c.Name,
+		Hostname:                 c.Hostname,
+		Resolution:               c.Resolution,
+		Status:                   c.Status,
+		Created:                  c.Created,
+		LastErrorTime:            c.LastErrorTime,
+		LastTestTime:             c.LastTestTime,
+		LastResponseTime:         c.LastResponseTime,
+		Paused:                   c.Status == "paused",
+		SendNotificationWhenDown: c.SendNotificationWhenDown,
+		NotifyAgainEvery:         c.NotifyAgainEvery,
+		IntegrationIds:           c.IntegrationIds,
+	}
+
+	var name string
+	var details map[string]json.RawMessage
+	if err := json.Unmarshal(c.Type, &name); err == nil {
+		r.Type.Name = name
+	} else if err := json.Unmarshal(c.Type, &details); err == nil {
+		for name := range details {
+			r.Type.Name = name
+		}
+	}
+	return r
+}
+
+// Error body of 3.1 and 2.0 responses.
+type v31Error struct {
+	Error struct {
+		StatusCode   int    `json:"statuscode"`
+		StatusDesc   string `json:"statusdesc"`
+		ErrorMessage string `json:"errormessage"`
+	} `json:"error"`
+}
+
+func (c *checksV31) List(params ...map[string]string) ([]pdom.CheckResponse, error) {
+	var body struct {
+		Checks []v31Check `json:"checks"`
+	}
+	if err := c.do(http.MethodGet, "/checks", formValues(params...), nil, &body); err != nil {
+		return nil, err
+	}
+
+	list := make([]pdom.CheckResponse, len(body.Checks))
+	for i := range body.Checks {
+		list[i] = body.Checks[i].response()
+	}
+	return list, nil
+}
+
+func (c *checksV31) Create(check pdom.Check) (*pdom.CheckResponse, error) {
+	if err := check.Valid(); err != nil {
+		return nil, err
+	}
+	req, err := v31Request(check, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		Check v31Check `json:"check"`
+	}
+	if err := c.do(http.MethodPost, "/checks", nil, req, &body); err != nil {
+		return nil, err
+	}
+	r := body.Check.response()
+	return &r, nil
+}
+
+func (c *checksV31) Read(id int) (*pdom.CheckResponse, error) {
+	var body struct {
+		Check v31Check `json:"check"`
+	}
+	if err := c.do(http.MethodGet, checkPath(id), nil, nil, &body); err != nil {
+		return nil, err
+	}
+	r := body.Check.response()
+	return &r, nil
+}
+
+func (c *checksV31) Update(id int, check pdom.Check) (*pdom.PingdomResponse, error) {
+	if err := check.Valid(); err != nil {
+		return nil, err
+	}
+	req, err := v31Request(check, false)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pdom.PingdomResponse{}
+	if err := c.do(http.MethodPut, checkPath(id), nil, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *checksV31) Delete(id int) (*pdom.PingdomResponse, error) {
+	resp := &pdom.PingdomResponse{}
+	if err := c.do(http.MethodDelete, checkPath(id), nil, nil, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func checkPath(id int) string {
+	return "/checks/" + strconv.Itoa(id)
+}
+
+// do sends the request with the JSON encoded body, if not nil, and decodes
+// the response into out.
+func (c *checksV31) do(method, path string, query url.Values, body, out interface{}) error {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, u, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return doRequest(c.client, req, out)
+}
+
+// doRequest sends the request and decodes the JSON response into out. Error
+// responses are returned as *pdom.PingdomError like the errors of
+// go-pingdom, so they are retried in the same way.
+func doRequest(client *http.Client, req *http.Request, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 400 {
+		perr := &pdom.PingdomError{StatusCode: resp.StatusCode, StatusDesc: http.StatusText(resp.StatusCode)}
+		var e v31Error
+		if json.Unmarshal(b, &e) == nil && e.Error.StatusCode != 0 {
+			perr.StatusCode = e.Error.StatusCode
+			perr.StatusDesc = e.Error.StatusDesc
+			perr.Message = e.Error.ErrorMessage
+		}
+		return perr
+	}
+	return json.Unmarshal(b, out)
+}
+
+// v31Request maps the check onto the JSON body of 3.1 requests. The type can
+// only be set when creating checks. Contacts are called users in 3.1.
+func v31Request(check pdom.Check, create bool) (map[string]interface{}, error) {
+	var m map[string]interface{}
+	var checkType string
+
+	switch ck := check.(type) {
+	case *pdom.HttpCheck:
+		checkType = tpr.CheckTypeHTTP
+		m = v31BaseRequest(baseCheck{
+			Name:                     ck.Name,
+			Hostname:                 ck.Hostname,
+			Resolution:               ck.Resolution,
+			Paused:                   ck.Paused,
+			SendNotificationWhenDown: ck.SendNotificationWhenDown,
+			NotifyAgainEvery:         ck.NotifyAgainEvery,
+			ContactIds:               ck.ContactIds,
+			IntegrationIds:           ck.IntegrationIds,
+		})
+		m["encryption"] = ck.Encryption
+		if ck.Url != "" {
+			m["url"] = ck.Url
+		}
+		if ck.Port != 0 {
+			m["port"] = ck.Port
+		}
+		if ck.ShouldContain != "" {
+			m["shouldcontain"] = ck.ShouldContain
+		}
+		if ck.ShouldNotContain != "" {
+			m["shouldnotcontain"] = ck.ShouldNotContain
+		}
+		if len(ck.RequestHeaders) > 0 {
+			m["requestheaders"] = ck.RequestHeaders
+		}
+		if ck.Username != "" {
+			m["auth"] = ck.Username + ":" + ck.Password
+		}
+	case *pdom.PingCheck:
+		checkType = tpr.CheckTypePing
+		m = v31BaseRequest(baseCheck{
+			Name:                     ck.Name,
+			Hostname:                 ck.Hostname,
+			Resolution:               ck.Resolution,
+			Paused:                   ck.Paused,
+			SendNotificationWhenDown: ck.SendNotificationWhenDown,
+			NotifyAgainEvery:         ck.NotifyAgainEvery,
+			ContactIds:               ck.ContactIds,
+			IntegrationIds:           ck.IntegrationIds,
+		})
+	case *tcpCheck:
+		checkType = tpr.CheckTypeTCP
+		m = v31BaseRequest(ck.baseCheck)
+		m["port"] = ck.Port
+		m["stringtosend"] = ck.StringToSend
+		m["stringtoexpect"] = ck.StringToExpect
+	case *dnsCheck:
+		checkType = tpr.CheckTypeDNS
+		m = v31BaseRequest(ck.baseCheck)
+		m["nameserver"] = ck.NameServer
+		m["expectedip"] = ck.ExpectedIP
+	default:
+		return nil, fmt.Errorf("unsupported check %T", check)
+	}
+
+	if create {
+		m["type"] = checkType
+	}
+	return m, nil
+}
+
+func v31BaseRequest(ck baseCheck) map[string]interface{} {
+	m := map[string]interface{}{
+		"name":             ck.Name,
+		"host":             ck.Hostname,
+		"resolution":       ck.Resolution,
+		"paused":           ck.Paused,
+		"notifyagainevery": ck.NotifyAgainEvery,
+	}
+	if ck.SendNotificationWhenDown != 0 {
+		m["sendnotificationwhendown"] = ck.SendNotificationWhenDown
+	}
+	if len(ck.ContactIds) > 0 {
+		m["userids"] = joinInts(ck.ContactIds)
+	}
+	if len(ck.IntegrationIds) > 0 {
+		m["integrationids"] = joinInts(ck.IntegrationIds)
+	}
+	return m
+}
@@ -0,0 +1,130 @@
+package pingdom
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rossf7/pingdom-operator/pkg/tpr"
+	pdom "github.com/russellcardullo/go-pingdom/pingdom"
+	"github.com/stretchr/testify/assert"
+)
+
+type v31Call struct {
+	method string
+	path   string
+	auth   string
+	body   map[string]interface{}
+}
+
+// Returns the 3.1 checks API of a server responding with the response of
+// the request path and recording the requests.
+func newTestChecksV31(t *testing.T, responses map[string]string) (*checksV31, *[]v31Call, func()) {
+	var calls []v31Call
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := v31Call{method: r.Method, path: r.URL.Path, auth: r.Header.Get("Authorization")}
+		if b, _ := ioutil.ReadAll(r.Body); len(b) > 0 {
+			assert.Nil(t, json.Unmarshal(b, &call.body))
+		}
+		calls = append(calls, call)
+
+		resp, ok := responses[r.Method+" "+r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"error":{"statuscode":404,"statusdesc":"Not Found","errormessage":"Check not found"}}`)
+			return
+		}
+		io.WriteString(w, resp)
+	}))
+
+	c := NewChecksV31("secret", nil).(*checksV31)
+	c.baseURL = srv.URL
+	return c, &calls, srv.Close
+}
+
+func TestChecksV31(t *testing.T) {
+	c, calls, stop := newTestChecksV31(t, map[string]string{
+		"GET /checks":      `{"checks":[{"id":1,"name":"[default/default/pets] cat.example.com","hostname":"cat.example.com","resolution":1,"status":"up","type":"http"}]}`,
+		"GET /checks/1":    `{"check":{"id":1,"name":"[default/default/pets] cat.example.com","hostname":"cat.example.com","status":"paused","sendnotificationwhendown":2,"type":{"http":{"url":"/","encryption":true}}}}`,
+		"POST /checks":     `{"check":{"id":2,"name":"[default/default/pets] dog.example.com"}}`,
+		"PUT /checks/1":    `{"message":"Modification of check was successful!"}`,
+		"DELETE /checks/1": `{"message":"Deletion of check was successful!"}`,
+	})
+	defer stop()
+
+	list, err := c.List()
+	assert.Nil(t, err)
+	assert.Len(t, list, 1)
+	assert.Equal(t, 1, list[0].ID)
+	assert.Equal(t, "cat.example.com", list[0].Hostname)
+	assert.Equal(t, tpr.CheckTypeHTTP, list[0].Type.Name)
+
+	r, err := c.Read(1)
+	assert.Nil(t, err)
+	assert.Equal(t, tpr.CheckTypeHTTP, r.Type.Name)
+	assert.Equal(t, 2, r.SendNotificationWhenDown)
+	assert.True(t, r.Paused)
+
+	r, err = c.Create(&pdom.HttpCheck{
+		Name:           "[default/default/pets] dog.example.com",
+		Hostname:       "dog.example.com",
+		Resolution:     5,
+		Encryption:     true,
+		Url:            "/healthz",
+		Username:       "user",
+		Password:       "pass",
+		RequestHeaders: map[string]string{"X-Check": "pingdom"},
+		ContactIds:     []int{1, 2},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, r.ID)
+
+	_, err = c.Update(1, &tcpCheck{baseCheck: baseCheck{Name: "tcp", Hostname: "cat.example.com"}, Port: 22})
+	assert.Nil(t, err)
+	_, err = c.Delete(1)
+	assert.Nil(t, err)
+
+	assert.Len(t, *calls, 5)
+	for _, call := range *calls {
+		assert.Equal(t, "Bearer secret", call.auth)
+	}
+
+	create := (*calls)[2]
+	assert.Equal(t, "POST", create.method)
+	assert.Equal(t, map[string]interface{}{
+		"name":             "[default/default/pets] dog.example.com",
+		"host":             "dog.example.com",
+		"type":             "http",
+		"resolution":       float64(5),
+		"paused":           false,
+		"notifyagainevery": float64(0),
+		"encryption":       true,
+		"url":              "/healthz",
+		"auth":             "user:pass",
+		"requestheaders":   map[string]interface{}{"X-Check": "pingdom"},
+		"userids":          "1,2",
+	}, create.body)
+
+	// The type can't be changed.
+	update := (*calls)[3]
+	assert.Equal(t, "/checks/1", update.path)
+	assert.Nil(t, update.body["type"])
+	assert.Equal(t, float64(22), update.body["port"])
+}
+
+func TestChecksV31Error(t *testing.T) {
+	c, _, stop := newTestChecksV31(t, nil)
+	defer stop()
+
+	_, err := c.Read(42)
+	assert.EqualError(t, err, "404 Not Found: Check not found")
+	perr, ok := err.(*pdom.PingdomError)
+	assert.True(t, ok)
+	assert.Equal(t, 404, perr.StatusCode)
+
+	_, err = c.Create(&pdom.PingCheck{Name: "ping"})
+	assert.NotNil(t, err, "invalid checks are not sent")
+}
@@ -0,0 +1,103 @@
+package pingdom
+
+import (
+	"sync"
+	"time"
+
+	pdom "github.com/russellcardullo/go-pingdom/pingdom"
+)
+
+// cachedChecks reuses the list of checks for ttl, so reconciling every
+// Ingress of a resync doesn't list all checks each time. Checks created,
+// updated and deleted through it are changed in the cached list. Changes
+// made in Pingdom by others are seen once the list expires.
+type cachedChecks struct {
+	checks ChecksAPI
+	ttl    time.Duration
+
+	mux     sync.Mutex
+	list    map[int]pdom.CheckResponse
+	expires time.Time
+	// Incremented by every change, a list started before a change is not
+	// cached.
+	gen uint64
+}
+
+func newCachedChecks(checks ChecksAPI, ttl time.Duration) *cachedChecks {
+	return &cachedChecks{checks: checks, ttl: ttl}
+}
+
+func (c *cachedChecks) List(params ...map[string]string) ([]pdom.CheckResponse, error) {
+	if len(params) > 0 {
+		return c.checks.List(params...)
+	}
+
+	c.mux.Lock()
+	if c.list != nil && time.Now().Before(c.expires) {
+		list := make([]pdom.CheckResponse, 0, len(c.list))
+		for _, r := range c.list {
+			list = append(list, r)
+		}
+		c.mux.Unlock()
+		return list, nil
+	}
+	gen := c.gen
+	c.mux.Unlock()
+
+	list, err := c.checks.List()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if gen == c.gen {
+		c.list = make(map[int]pdom.CheckResponse, len(list))
+		for _, r := range list {
+			c.list[r.ID] = r
+		}
+		c.expires = time.Now().Add(c.ttl)
+	}
+	return list, nil
+}
+
+func (c *cachedChecks) Read(id int) (*pdom.CheckResponse, error) {
+	return c.checks.Read(id)
+}
+
+func (c *cachedChecks) Create(check pdom.Check) (*pdom.CheckResponse, error) {
+	resp, err := c.checks.Create(check)
+	c.changed(func() { c.list[resp.ID] = checkResponse(resp.ID, check) }, err)
+	return resp, err
+}
+
+func (c *cachedChecks) Update(id int, check pdom.Check) (*pdom.PingdomResponse, error) {
+	resp, err := c.checks.Update(id, check)
+	c.changed(func() {
+		if _, ok := c.list[id]; ok {
+			c.list[id] = checkResponse(id, check)
+		}
+	}, err)
+	return resp, err
+}
+
+func (c *cachedChecks) Delete(id int) (*pdom.PingdomResponse, error) {
+	resp, err := c.checks.Delete(id)
+	c.changed(func() { delete(c.list, id) }, err)
+	return resp, err
+}
+
+// changed applies the change to the cached list, or drops the list if the
+// request failed as the check may have been changed anyway.
+func (c *cachedChecks) changed(apply func(), err error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.gen++
+	switch {
+	case c.list == nil:
+	case err != nil:
+		c.list = nil
+	default:
+		apply()
+	}
+}
@@ -0,0 +1,65 @@
+package pingdom
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	pdom "github.com/russellcardullo/go-pingdom/pingdom"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rossf7/pingdom-operator/pkg/pingdom/fake"
+)
+
+func TestCachedChecks(t *testing.T) {
+	p := fake.New()
+	p.Add(newCheckResponse("[default/default/pets] cats.example.com", "cats.example.com"))
+	c := newCachedChecks(p, time.Minute)
+
+	hosts := func() []string {
+		list, err := c.List()
+		assert.Nil(t, err)
+		var hosts []string
+		for _, r := range list {
+			hosts = append(hosts, r.Hostname)
+		}
+		sort.Strings(hosts)
+		return hosts
+	}
+
+	assert.Equal(t, []string{"cats.example.com"}, hosts())
+	assert.Equal(t, []string{"cats.example.com"}, hosts())
+	assert.Equal(t, 1, p.Calls(fake.OpList))
+
+	// Changes are applied to the cached list.
+	resp, err := c.Create(&pdom.HttpCheck{Name: "[default/default/pets] dogs.example.com", Hostname: "dogs.example.com", Resolution: 5})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"cats.example.com", "dogs.example.com"}, hosts())
+	_, err = c.Update(resp.ID, &pdom.HttpCheck{Name: "[default/default/pets] dogs.example.com", Hostname: "dogs.example.com", Resolution: 15})
+	assert.Nil(t, err)
+	list, _ := c.List()
+	for _, r := range list {
+		if r.ID == resp.ID {
+			assert.Equal(t, 15, r.Resolution)
+		}
+	}
+	_, err = c.Delete(resp.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"cats.example.com"}, hosts())
+	assert.Equal(t, 1, p.Calls(fake.OpList))
+
+	// The list is dropped after a failed change, the check may have been
+	// created anyway.
+	p.FailCommitted(fake.OpCreate, 1, &pdom.PingdomError{StatusCode: 502, StatusDesc: "Bad Gateway"})
+	_, err = c.Create(&pdom.HttpCheck{Name: "[default/default/pets] birds.example.com", Hostname: "birds.example.com"})
+	assert.NotNil(t, err)
+	assert.Equal(t, []string{"birds.example.com", "cats.example.com"}, hosts())
+	assert.Equal(t, 2, p.Calls(fake.OpList))
+
+	// Checks changed by others are listed once the list expires.
+	p.Add(newCheckResponse("[default/default/pets] fish.example.com", "fish.example.com"))
+	assert.Equal(t, []string{"birds.example.com", "cats.example.com"}, hosts())
+	c.expires = time.Now()
+	assert.Equal(t, []string{"birds.example.com", "cats.example.com", "fish.example.com"}, hosts())
+	assert.Equal(t, 3, p.Calls(fake.OpList))
+}
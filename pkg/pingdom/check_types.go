@@ -0,0 +1,188 @@
+package pingdom
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rossf7/pingdom-operator/pkg/tpr"
+	pdom "github.com/russellcardullo/go-pingdom/pingdom"
+)
+
+// Returned when updating a check whose type differs from the spec. Pingdom
+// can't change the type of a check, it needs to be recreated.
+var errCheckTypeChanged = errors.New("check type changed")
+
+// Builds the check for the host according to the type of the spec.
+func (c *Operator) buildCheck(namespace, name, host string, checkSpec tpr.Spec) (pdom.Check, error) {
+	if err := checkSpec.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch checkSpec.CheckType() {
+	case tpr.CheckTypeTCP:
+		return newTCPCheck(name, host, checkSpec), nil
+	case tpr.CheckTypeDNS:
+		return newDNSCheck(name, host, checkSpec), nil
+	case tpr.CheckTypePing:
+		return newPingCheck(name, host, checkSpec), nil
+	default:
+		hc, err := c.httpCheck(namespace, name, host, checkSpec)
+		if err != nil {
+			return nil, err
+		}
+		return hc, nil
+	}
+}
+
+func newPingCheck(name, host string, checkSpec tpr.Spec) *pdom.PingCheck {
+	return &pdom.PingCheck{
+		Name:                     name,
+		Hostname:                 host,
+		Resolution:               checkSpec.Resolution,
+		Paused:                   checkSpec.Paused,
+		SendNotificationWhenDown: checkSpec.NotifyAfterFailures,
+		NotifyAgainEvery:         checkSpec.NotifyAgainEvery,
+		ContactIds:               checkSpec.ContactIDs,
+		IntegrationIds:           checkSpec.IntegrationIDs,
+	}
+}
+
+func newTCPCheck(name, host string, checkSpec tpr.Spec) *tcpCheck {
+	ck := &tcpCheck{
+		baseCheck: newBaseCheck(name, host, checkSpec),
+		Port:      checkSpec.Port,
+	}
+	if checkSpec.TCP != nil {
+		ck.StringToSend = checkSpec.TCP.StringToSend
+		ck.StringToExpect = checkSpec.TCP.StringToExpect
+	}
+	return ck
+}
+
+func newDNSCheck(name, host string, checkSpec tpr.Spec) *dnsCheck {
+	return &dnsCheck{
+		baseCheck:  newBaseCheck(name, host, checkSpec),
+		NameServer: checkSpec.DNS.NameServer,
+		ExpectedIP: checkSpec.DNS.ExpectedIP,
+	}
+}
+
+// baseCheck holds the parameters common to all check types. go-pingdom only
+// supports HTTP and ping checks, other types implement pdom.Check here.
+type baseCheck struct {
+	Name                     string
+	Hostname                 string
+	Resolution               int
+	Paused                   bool
+	SendNotificationWhenDown int
+	NotifyAgainEvery         int
+	ContactIds               []int
+	IntegrationIds           []int
+}
+
+func newBaseCheck(name, host string, checkSpec tpr.Spec) baseCheck {
+	return baseCheck{
+		Name:                     name,
+		Hostname:                 host,
+		Resolution:               checkSpec.Resolution,
+		Paused:                   checkSpec.Paused,
+		SendNotificationWhenDown: checkSpec.NotifyAfterFailures,
+		NotifyAgainEvery:         checkSpec.NotifyAgainEvery,
+		ContactIds:               checkSpec.ContactIDs,
+		IntegrationIds:           checkSpec.IntegrationIDs,
+	}
+}
+
+func (ck *baseCheck) params() map[string]string {
+	m := map[string]string{
+		"name":             ck.Name,
+		"host":             ck.Hostname,
+		"resolution":       strconv.Itoa(ck.Resolution),
+		"paused":           strconv.FormatBool(ck.Paused),
+		"notifyagainevery": strconv.Itoa(ck.NotifyAgainEvery),
+	}
+	if ck.SendNotificationWhenDown != 0 {
+		m["sendnotificationwhendown"] = strconv.Itoa(ck.SendNotificationWhenDown)
+	}
+	if len(ck.ContactIds) > 0 {
+		m["contactids"] = joinInts(ck.ContactIds)
+	}
+	if len(ck.IntegrationIds) > 0 {
+		m["integrationids"] = joinInts(ck.IntegrationIds)
+	}
+	return m
+}
+
+func (ck *baseCheck) valid() error {
+	if ck.Name == "" {
+		return fmt.Errorf("invalid value for `Name`. Must contain non-empty string")
+	}
+	if ck.Hostname == "" {
+		return fmt.Errorf("invalid value for `Hostname`. Must contain non-empty string")
+	}
+	return nil
+}
+
+type tcpCheck struct {
+	baseCheck
+	Port           int
+	StringToSend   string
+	StringToExpect string
+}
+
+func (ck *tcpCheck) PutParams() map[string]string {
+	m := ck.params()
+	m["port"] = strconv.Itoa(ck.Port)
+	m["stringtosend"] = ck.StringToSend
+	m["stringtoexpect"] = ck.StringToExpect
+	return m
+}
+
+func (ck *tcpCheck) PostParams() map[string]string {
+	m := ck.PutParams()
+	m["type"] = tpr.CheckTypeTCP
+	return m
+}
+
+func (ck *tcpCheck) Valid() error {
+	if ck.Port <= 0 {
+		return fmt.Errorf("invalid value for `Port`. Must be positive")
+	}
+	return ck.valid()
+}
+
+type dnsCheck struct {
+	baseCheck
+	NameServer string
+	ExpectedIP string
+}
+
+func (ck *dnsCheck) PutParams() map[string]string {
+	m := ck.params()
+	m["nameserver"] = ck.NameServer
+	m["expectedip"] = ck.ExpectedIP
+	return m
+}
+
+func (ck *dnsCheck) PostParams() map[string]string {
+	m := ck.PutParams()
+	m["type"] = tpr.CheckTypeDNS
+	return m
+}
+
+func (ck *dnsCheck) Valid() error {
+	if ck.NameServer == "" || ck.ExpectedIP == "" {
+		return fmt.Errorf("invalid value for `NameServer` or `ExpectedIP`. Must contain non-empty string")
+	}
+	return ck.valid()
+}
+
+func joinInts(ints []int) string {
+	s := make([]string, len(ints))
+	for i, n := range ints {
+		s[i] = strconv.Itoa(n)
+	}
+	return strings.Join(s, ",")
+}
@@ -0,0 +1,57 @@
+package pingdom
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rossf7/pingdom-operator/pkg/tpr"
+)
+
+func TestBuildTCPCheck(t *testing.T) {
+	o := &Operator{}
+	spec := tpr.Spec{
+		Type:       "TCP",
+		Resolution: 5,
+		Port:       22,
+		ContactIDs: []int{1, 2},
+		TCP:        &tpr.TCPSpec{StringToExpect: "SSH"},
+	}
+
+	ck, err := o.buildCheck("default", "ssh", "ssh.example.com", spec)
+
+	assert.Nil(t, err)
+	assert.Nil(t, ck.Valid())
+	params := ck.PostParams()
+	assert.Equal(t, "tcp", params["type"])
+	assert.Equal(t, "ssh.example.com", params["host"])
+	assert.Equal(t, "22", params["port"])
+	assert.Equal(t, "SSH", params["stringtoexpect"])
+	assert.Equal(t, "1,2", params["contactids"])
+	_, ok := ck.PutParams()["type"]
+	assert.False(t, ok)
+}
+
+func TestBuildDNSCheck(t *testing.T) {
+	o := &Operator{}
+	spec := tpr.Spec{
+		Type: tpr.CheckTypeDNS,
+		DNS:  &tpr.DNSSpec{NameServer: "8.8.8.8", ExpectedIP: "10.0.0.1"},
+	}
+
+	ck, err := o.buildCheck("default", "dns", "example.com", spec)
+
+	assert.Nil(t, err)
+	params := ck.PostParams()
+	assert.Equal(t, "dns", params["type"])
+	assert.Equal(t, "8.8.8.8", params["nameserver"])
+	assert.Equal(t, "10.0.0.1", params["expectedip"])
+}
+
+func TestBuildCheckInvalid(t *testing.T) {
+	o := &Operator{}
+
+	_, err := o.buildCheck("default", "dns", "example.com", tpr.Spec{Type: tpr.CheckTypeDNS})
+
+	assert.NotNil(t, err)
+}
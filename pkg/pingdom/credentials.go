@@ -0,0 +1,294 @@
+package pingdom
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	pdom "github.com/russellcardullo/go-pingdom/pingdom"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/pkg/api"
+	"k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/pkg/fields"
+	"k8s.io/client-go/pkg/runtime"
+	"k8s.io/client-go/pkg/util/wait"
+	"k8s.io/client-go/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Keys of the credentials in the Secret, which are also the names of the
+// files of the mounted Secret.
+const (
+	credentialsKeyToken    = "api-token"
+	credentialsKeyUser     = "api-user"
+	credentialsKeyPassword = "api-password"
+	credentialsKeyAppKey   = "api-key"
+)
+
+// Interval of reading the credentials of a mounted Secret. The kubelet
+// updates mounted Secrets about once a minute.
+const credentialsPollPeriod = 30 * time.Second
+
+var errNoCredentials = errors.New("no Pingdom credentials loaded")
+
+// Credentials authenticate Pingdom API requests. The 3.1 API uses the
+// token, the 2.0 API the user, password and application key.
+type Credentials struct {
+	Token    string
+	User     string
+	Password string
+	AppKey   string
+}
+
+// CredentialsFromEnv reads the credentials from PINGDOM_API_TOKEN,
+// PINGDOM_USER, PINGDOM_PASSWORD and PINGDOM_API_KEY.
+func CredentialsFromEnv() Credentials {
+	return Credentials{
+		Token:    os.Getenv("PINGDOM_API_TOKEN"),
+		User:     os.Getenv("PINGDOM_USER"),
+		Password: os.Getenv("PINGDOM_PASSWORD"),
+		AppKey:   os.Getenv("PINGDOM_API_KEY"),
+	}
+}
+
+func credentialsFromData(data map[string][]byte) Credentials {
+	get := func(key string) string {
+		return strings.TrimSpace(string(data[key]))
+	}
+	return Credentials{
+		Token:    get(credentialsKeyToken),
+		User:     get(credentialsKeyUser),
+		Password: get(credentialsKeyPassword),
+		AppKey:   get(credentialsKeyAppKey),
+	}
+}
+
+// credentialsFromDir reads the credentials from the files of a mounted
+// Secret. Missing files are skipped.
+func credentialsFromDir(dir string) (Credentials, error) {
+	data := make(map[string][]byte)
+	for _, key := range []string{credentialsKeyToken, credentialsKeyUser, credentialsKeyPassword, credentialsKeyAppKey} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, key))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return Credentials{}, err
+		}
+		data[key] = b
+	}
+	return credentialsFromData(data), nil
+}
+
+// NewChecksAPI returns the checks API of the Pingdom API version
+// authenticating with the credentials. Without a version, the 3.1 API is
+// used unless only the legacy 2.0 credentials are set, so deployments
+// setting those keep working. Requests are sent with http.DefaultClient if
+// client is nil.
+func NewChecksAPI(version string, creds Credentials, client *http.Client) (ChecksAPI, error) {
+	if version == "" {
+		version = APIVersion31
+		if creds.Token == "" && (creds.User != "" || creds.Password != "" || creds.AppKey != "") {
+			log.Warning("Using the legacy Pingdom 2.0 API as no API token is set")
+			version = APIVersion2
+		}
+	}
+
+	switch version {
+	case APIVersion2:
+		if creds.User == "" || creds.Password == "" || creds.AppKey == "" {
+			return nil, fmt.Errorf("the Pingdom %s API needs a user, password and application key", version)
+		}
+		return NewChecksV2(creds.User, creds.Password, creds.AppKey, client), nil
+	case APIVersion31:
+		if creds.Token == "" {
+			return nil, fmt.Errorf("the Pingdom %s API needs an API token", version)
+		}
+		return NewChecksV31(creds.Token, client), nil
+	default:
+		return nil, fmt.Errorf("unsupported Pingdom API version %q", version)
+	}
+}
+
+// ReloadingChecks calls the checks API with the current credentials. When
+// the credentials change the client is swapped, calls in flight finish
+// with the previous client.
+type ReloadingChecks struct {
+	version string
+	client  *http.Client
+
+	mux       sync.RWMutex
+	creds     Credentials
+	checks    ChecksAPI
+	reloadErr error
+}
+
+// NewReloadingChecks returns the checks API of the Pingdom API version,
+// picked by the credentials if empty, which fails until credentials are
+// set.
+func NewReloadingChecks(version string, client *http.Client) *ReloadingChecks {
+	return &ReloadingChecks{version: version, client: client}
+}
+
+// SetCredentials swaps the client if the credentials changed. Invalid
+// credentials are rejected and the previous client is kept.
+func (r *ReloadingChecks) SetCredentials(creds Credentials) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if r.checks != nil && creds == r.creds {
+		r.reloadErr = nil
+		return nil
+	}
+	checks, err := NewChecksAPI(r.version, creds, r.client)
+	if err != nil {
+		r.reloadErr = err
+		return err
+	}
+	if r.checks != nil {
+		log.Info("Pingdom credentials changed, swapping the client")
+	}
+	r.creds = creds
+	r.checks = checks
+	r.reloadErr = nil
+	return nil
+}
+
+// Ready returns an error if no credentials are loaded or the last reload
+// failed.
+func (r *ReloadingChecks) Ready() error {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	if r.reloadErr != nil {
+		return fmt.Errorf("invalid Pingdom credentials: %v", r.reloadErr)
+	}
+	if r.checks == nil {
+		return errNoCredentials
+	}
+	return nil
+}
+
+func (r *ReloadingChecks) current() (ChecksAPI, error) {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	if r.checks == nil {
+		return nil, errNoCredentials
+	}
+	return r.checks, nil
+}
+
+func (r *ReloadingChecks) List(params ...map[string]string) ([]pdom.CheckResponse, error) {
+	checks, err := r.current()
+	if err != nil {
+		return nil, err
+	}
+	return checks.List(params...)
+}
+
+func (r *ReloadingChecks) Create(check pdom.Check) (*pdom.CheckResponse, error) {
+	checks, err := r.current()
+	if err != nil {
+		return nil, err
+	}
+	return checks.Create(check)
+}
+
+func (r *ReloadingChecks) Read(id int) (*pdom.CheckResponse, error) {
+	checks, err := r.current()
+	if err != nil {
+		return nil, err
+	}
+	return checks.Read(id)
+}
+
+func (r *ReloadingChecks) Update(id int, check pdom.Check) (*pdom.PingdomResponse, error) {
+	checks, err := r.current()
+	if err != nil {
+		return nil, err
+	}
+	return checks.Update(id, check)
+}
+
+func (r *ReloadingChecks) Delete(id int) (*pdom.PingdomResponse, error) {
+	checks, err := r.current()
+	if err != nil {
+		return nil, err
+	}
+	return checks.Delete(id)
+}
+
+// WatchSecret sets the credentials of the Secret whenever it changes, until
+// stopc is closed. The credentials are kept if the Secret is deleted.
+func (r *ReloadingChecks) WatchSecret(kclient kubernetes.Interface, namespace, name string, stopc <-chan struct{}) {
+	logger := log.WithFields(logrus.Fields{"namespace": namespace, "secret": name})
+	secrets := kclient.CoreV1().Secrets(namespace)
+	selector := fields.OneTermEqualSelector("metadata.name", name).String()
+
+	update := func(obj interface{}) {
+		secret, ok := obj.(*v1.Secret)
+		if !ok {
+			return
+		}
+		if err := r.SetCredentials(credentialsFromData(secret.Data)); err != nil {
+			logger.WithError(err).Error("Error loading Pingdom credentials from Secret")
+		}
+	}
+
+	_, controller := cache.NewInformer(
+		&cache.ListWatch{
+			ListFunc: func(options api.ListOptions) (runtime.Object, error) {
+				var v1Options v1.ListOptions
+				v1.Convert_api_ListOptions_To_v1_ListOptions(&options, &v1Options, nil)
+				v1Options.FieldSelector = selector
+				return secrets.List(v1Options)
+			},
+			WatchFunc: func(options api.ListOptions) (watch.Interface, error) {
+				var v1Options v1.ListOptions
+				v1.Convert_api_ListOptions_To_v1_ListOptions(&options, &v1Options, nil)
+				v1Options.FieldSelector = selector
+				return secrets.Watch(v1Options)
+			},
+		},
+		&v1.Secret{}, 0,
+		cache.ResourceEventHandlerFuncs{
+			AddFunc:    update,
+			UpdateFunc: func(_, obj interface{}) { update(obj) },
+			DeleteFunc: func(obj interface{}) {
+				logger.Warning("Pingdom credentials Secret deleted, keeping the current credentials")
+			},
+		},
+	)
+	controller.Run(stopc)
+}
+
+// WatchDir reads the credentials from the files of a mounted Secret
+// periodically, until stopc is closed.
+func (r *ReloadingChecks) WatchDir(dir string, stopc <-chan struct{}) {
+	wait.Until(func() {
+		if err := r.LoadDir(dir); err != nil {
+			log.WithField("dir", dir).WithError(err).Error("Error loading Pingdom credentials from files")
+		}
+	}, credentialsPollPeriod, stopc)
+}
+
+// LoadDir sets the credentials from the files of a mounted Secret.
+func (r *ReloadingChecks) LoadDir(dir string) error {
+	creds, err := credentialsFromDir(dir)
+	if err != nil {
+		r.mux.Lock()
+		r.reloadErr = err
+		r.mux.Unlock()
+		return err
+	}
+	return r.SetCredentials(creds)
+}
@@ -0,0 +1,142 @@
+package pingdom
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pdom "github.com/russellcardullo/go-pingdom/pingdom"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rossf7/pingdom-operator/pkg/pingdom/fake"
+
+	kfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/pkg/util/wait"
+	"k8s.io/client-go/pkg/watch"
+	core "k8s.io/client-go/testing"
+)
+
+// Returns the token of the current 3.1 client.
+func currentToken(r *ReloadingChecks) string {
+	checks, err := r.current()
+	if err != nil {
+		return ""
+	}
+	return checks.(*checksV31).token
+}
+
+func TestReloadingChecks(t *testing.T) {
+	r := NewReloadingChecks(APIVersion31, nil)
+	_, err := r.List()
+	assert.Equal(t, errNoCredentials, err)
+	assert.Equal(t, errNoCredentials, r.Ready())
+
+	assert.Nil(t, r.SetCredentials(Credentials{Token: "a"}))
+	assert.Nil(t, r.Ready())
+	assert.Equal(t, "a", currentToken(r))
+	checks, _ := r.current()
+
+	assert.Nil(t, r.SetCredentials(Credentials{Token: "a"}))
+	current, _ := r.current()
+	assert.True(t, checks == current, "unchanged credentials keep the client")
+
+	assert.Nil(t, r.SetCredentials(Credentials{Token: "b"}))
+	assert.Equal(t, "b", currentToken(r))
+
+	// Invalid credentials keep the previous client.
+	assert.NotNil(t, r.SetCredentials(Credentials{User: "user", Password: "pass", AppKey: "key"}))
+	assert.NotNil(t, r.Ready())
+	assert.Equal(t, "b", currentToken(r))
+}
+
+func TestNewChecksAPIVersion(t *testing.T) {
+	legacy := Credentials{User: "user", Password: "pass", AppKey: "key"}
+
+	checks, err := NewChecksAPI("", legacy, nil)
+	assert.Nil(t, err)
+	assert.IsType(t, &checksV2{}, checks)
+
+	checks, err = NewChecksAPI("", Credentials{Token: "a", User: "user", Password: "pass", AppKey: "key"}, nil)
+	assert.Nil(t, err)
+	assert.IsType(t, &checksV31{}, checks)
+
+	_, err = NewChecksAPI("", Credentials{}, nil)
+	assert.EqualError(t, err, "the Pingdom 3.1 API needs an API token")
+	_, err = NewChecksAPI(APIVersion31, legacy, nil)
+	assert.NotNil(t, err)
+	_, err = NewChecksAPI("", Credentials{User: "user"}, nil)
+	assert.EqualError(t, err, "the Pingdom 2.0 API needs a user, password and application key")
+}
+
+func TestCredentialsFromDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pingdom-credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	r := NewReloadingChecks(APIVersion2, nil)
+	assert.NotNil(t, r.LoadDir(dir))
+
+	for key, value := range map[string]string{
+		"api-user":     "user",
+		"api-password": "pass",
+		"api-key":      "key\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, key), []byte(value), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assert.Nil(t, r.LoadDir(dir))
+	assert.Nil(t, r.Ready())
+	assert.Equal(t, Credentials{User: "user", Password: "pass", AppKey: "key"}, r.creds)
+}
+
+func TestWatchSecret(t *testing.T) {
+	kclient := kfake.NewSimpleClientset(&v1.Secret{
+		ObjectMeta: v1.ObjectMeta{Namespace: "monitoring", Name: "pingdom-secret"},
+		Data:       map[string][]byte{"api-token": []byte("a")},
+	})
+	// The fake clientset does not send watch events.
+	watcher := watch.NewFake()
+	kclient.PrependWatchReactor("secrets", core.DefaultWatchReactor(watcher, nil))
+
+	r := NewReloadingChecks(APIVersion31, nil)
+	stopc := make(chan struct{})
+	defer close(stopc)
+	go r.WatchSecret(kclient, "monitoring", "pingdom-secret", stopc)
+
+	waitForToken := func(token string) {
+		err := wait.Poll(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+			return currentToken(r) == token, nil
+		})
+		assert.Nil(t, err, "token %q not loaded", token)
+	}
+	waitForToken("a")
+
+	watcher.Modify(&v1.Secret{
+		ObjectMeta: v1.ObjectMeta{Namespace: "monitoring", Name: "pingdom-secret"},
+		Data:       map[string][]byte{"api-token": []byte("b")},
+	})
+	waitForToken("b")
+
+	// The credentials are kept when the Secret is deleted.
+	watcher.Delete(&v1.Secret{ObjectMeta: v1.ObjectMeta{Namespace: "monitoring", Name: "pingdom-secret"}})
+	assert.Nil(t, r.Ready())
+	assert.Equal(t, "b", currentToken(r))
+}
+
+func TestReadyCredentialsRejected(t *testing.T) {
+	h := newHarness(t, DefaultConfig())
+	defer h.stop()
+
+	h.pingdom.Fail(fake.OpList, -1, &pdom.PingdomError{StatusCode: 401, StatusDesc: "Unauthorized"})
+	assert.EqualError(t, h.o.Ready(), "pingdom api: credentials rejected: 401 Unauthorized: ")
+
+	// Rejected credentials are tested again on the next call.
+	h.pingdom.Fail(fake.OpList, 0, nil)
+	assert.Nil(t, h.o.Ready())
+}
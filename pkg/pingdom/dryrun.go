@@ -0,0 +1,251 @@
+package pingdom
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rossf7/pingdom-operator/pkg/tpr"
+	pdom "github.com/russellcardullo/go-pingdom/pingdom"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/client-go/pkg/apis/extensions/v1beta1"
+)
+
+// DefaultDryRunAnnotation is the annotation key of the checks planned in
+// dry-run mode.
+const DefaultDryRunAnnotation = "monitoring.rossfairbanks.com/pingdom_checks_dry_run"
+
+var dryRunCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: metricsNamespace,
+	Name:      "dry_run_calls_total",
+	Help:      "Pingdom API calls skipped in dry-run mode by operation.",
+}, []string{"operation"})
+
+func init() {
+	prometheus.MustRegister(dryRunCalls)
+}
+
+// dryRunChecks reads checks from Pingdom but only logs the checks it would
+// create, update and delete. The planned changes are kept in memory so
+// later syncs see them and don't plan the same changes again. Created
+// checks get negative IDs.
+type dryRunChecks struct {
+	checks ChecksAPI
+
+	mux     sync.Mutex
+	lastID  int
+	planned map[int]pdom.CheckResponse
+	deleted map[int]bool
+}
+
+func newDryRunChecks(checks ChecksAPI) *dryRunChecks {
+	return &dryRunChecks{
+		checks:  checks,
+		planned: make(map[int]pdom.CheckResponse),
+		deleted: make(map[int]bool),
+	}
+}
+
+func (d *dryRunChecks) List(params ...map[string]string) ([]pdom.CheckResponse, error) {
+	list, err := d.checks.List(params...)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	var checks []pdom.CheckResponse
+	for _, r := range list {
+		if _, ok := d.planned[r.ID]; ok || d.deleted[r.ID] {
+			continue
+		}
+		checks = append(checks, r)
+	}
+	for _, r := range d.planned {
+		checks = append(checks, r)
+	}
+	return checks, nil
+}
+
+func (d *dryRunChecks) Read(id int) (*pdom.CheckResponse, error) {
+	d.mux.Lock()
+	r, ok := d.planned[id]
+	deleted := d.deleted[id]
+	d.mux.Unlock()
+
+	if ok {
+		return &r, nil
+	}
+	if deleted || id < 0 {
+		return nil, &pdom.PingdomError{StatusCode: http.StatusNotFound, StatusDesc: "Not Found", Message: "check deleted in dry run"}
+	}
+	return d.checks.Read(id)
+}
+
+func (d *dryRunChecks) Create(check pdom.Check) (*pdom.CheckResponse, error) {
+	d.mux.Lock()
+	d.lastID--
+	r := checkResponse(d.lastID, check)
+	d.planned[r.ID] = r
+	d.mux.Unlock()
+
+	dryRunCalls.WithLabelValues("createCheck").Inc()
+	dryRunLogger("createCheck", r).Infof("Dry run: would create %s check", r.Type.Name)
+	return &r, nil
+}
+
+func (d *dryRunChecks) Update(id int, check pdom.Check) (*pdom.PingdomResponse, error) {
+	if _, err := d.Read(id); err != nil {
+		return nil, err
+	}
+
+	r := checkResponse(id, check)
+	d.mux.Lock()
+	d.planned[id] = r
+	d.mux.Unlock()
+
+	dryRunCalls.WithLabelValues("updateCheck").Inc()
+	dryRunLogger("updateCheck", r).Info("Dry run: would update check")
+	return &pdom.PingdomResponse{Message: "dry run"}, nil
+}
+
+func (d *dryRunChecks) Delete(id int) (*pdom.PingdomResponse, error) {
+	r, err := d.Read(id)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mux.Lock()
+	delete(d.planned, id)
+	d.deleted[id] = true
+	d.mux.Unlock()
+
+	dryRunCalls.WithLabelValues("deleteCheck").Inc()
+	dryRunLogger("deleteCheck", *r).Info("Dry run: would delete check")
+	return &pdom.PingdomResponse{Message: "dry run"}, nil
+}
+
+func dryRunLogger(operation string, r pdom.CheckResponse) *logrus.Entry {
+	return log.WithFields(logrus.Fields{
+		"operation":  operation,
+		"host":       r.Hostname,
+		"check_id":   r.ID,
+		"check_name": r.Name,
+	})
+}
+
+// Returns the response Pingdom would return for the check. Only the fields
+// compared when reconciling are set.
+func checkResponse(id int, check pdom.Check) pdom.CheckResponse {
+	r := pdom.CheckResponse{ID: id}
+	var paused bool
+	switch ck := check.(type) {
+	case *pdom.HttpCheck:
+		r.Name, r.Hostname, r.Resolution, paused = ck.Name, ck.Hostname, ck.Resolution, ck.Paused
+		r.Type.Name = tpr.CheckTypeHTTP
+	case *pdom.PingCheck:
+		r.Name, r.Hostname, r.Resolution, paused = ck.Name, ck.Hostname, ck.Resolution, ck.Paused
+		r.Type.Name = tpr.CheckTypePing
+	case *tcpCheck:
+		r.Name, r.Hostname, r.Resolution, paused = ck.Name, ck.Hostname, ck.Resolution, ck.Paused
+		r.Type.Name = tpr.CheckTypeTCP
+	case *dnsCheck:
+		r.Name, r.Hostname, r.Resolution, paused = ck.Name, ck.Hostname, ck.Resolution, ck.Paused
+		r.Type.Name = tpr.CheckTypeDNS
+	}
+	r.Status = "up"
+	if paused {
+		r.Status = "paused"
+	}
+	return r
+}
+
+// plannedChecks are the checks of Ingresses in dry-run mode by Ingress
+// key. They are used instead of the checks annotation, which is left as it
+// is.
+type plannedChecks struct {
+	mux  sync.Mutex
+	data map[string]map[string]int
+}
+
+func newPlannedChecks() *plannedChecks {
+	return &plannedChecks{data: make(map[string]map[string]int)}
+}
+
+func (p *plannedChecks) get(ing *v1beta1.Ingress) (map[string]int, bool) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	planned, ok := p.data[ing.Namespace+"/"+ing.Name]
+	if !ok {
+		return nil, false
+	}
+	checks := make(map[string]int, len(planned))
+	for host, id := range planned {
+		checks[host] = id
+	}
+	return checks, true
+}
+
+func (p *plannedChecks) set(ing *v1beta1.Ingress, checks map[string]int) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	planned := make(map[string]int, len(checks))
+	for host, id := range checks {
+		planned[host] = id
+	}
+	p.data[ing.Namespace+"/"+ing.Name] = planned
+}
+
+func (p *plannedChecks) forget(key string) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	delete(p.data, key)
+}
+
+// Records the planned checks of the Ingress instead of setting the checks
+// annotation and finalizer. They are shown in the dry-run annotation,
+// unless its key is empty.
+func (o *Operator) setPlannedChecks(ing *v1beta1.Ingress, checks map[string]int) error {
+	o.planned.set(ing, checks)
+
+	key := o.config.DryRunAnnotation
+	if key == "" {
+		return nil
+	}
+
+	// Get a fresh copy of the ingress before updating.
+	ing, err := o.kclient.ExtensionsV1beta1().Ingresses(ing.Namespace).Get(ing.Name)
+	if err != nil {
+		return fmt.Errorf("getting ingress: %v", err)
+	}
+
+	data := ""
+	if len(checks) > 0 {
+		bytes, _ := json.Marshal(checks)
+		data = string(bytes)
+	}
+	if ing.ObjectMeta.Annotations[key] == data {
+		return nil
+	}
+	if data == "" {
+		delete(ing.ObjectMeta.Annotations, key)
+	} else {
+		if ing.ObjectMeta.Annotations == nil {
+			ing.ObjectMeta.Annotations = make(map[string]string)
+		}
+		ing.ObjectMeta.Annotations[key] = data
+	}
+
+	_, err = o.kclient.ExtensionsV1beta1().Ingresses(ing.Namespace).Update(ing)
+	if err != nil {
+		return fmt.Errorf("updating ingress: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package pingdom
+
+import (
+	"testing"
+
+	pdom "github.com/russellcardullo/go-pingdom/pingdom"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rossf7/pingdom-operator/pkg/tpr"
+
+	"k8s.io/client-go/pkg/apis/extensions/v1beta1"
+)
+
+// readOnlyChecks fails the test on any change to the checks.
+type readOnlyChecks struct {
+	t      *testing.T
+	checks []pdom.CheckResponse
+}
+
+func (c readOnlyChecks) List(params ...map[string]string) ([]pdom.CheckResponse, error) {
+	return c.checks, nil
+}
+
+func (c readOnlyChecks) Read(id int) (*pdom.CheckResponse, error) {
+	for _, r := range c.checks {
+		if r.ID == id {
+			return &r, nil
+		}
+	}
+	return nil, &pdom.PingdomError{StatusCode: 404}
+}
+
+func (c readOnlyChecks) Create(check pdom.Check) (*pdom.CheckResponse, error) {
+	c.t.Fatal("check created")
+	return nil, nil
+}
+
+func (c readOnlyChecks) Update(id int, check pdom.Check) (*pdom.PingdomResponse, error) {
+	c.t.Fatalf("check %d updated", id)
+	return nil, nil
+}
+
+func (c readOnlyChecks) Delete(id int) (*pdom.PingdomResponse, error) {
+	c.t.Fatalf("check %d deleted", id)
+	return nil, nil
+}
+
+func TestDryRunChecks(t *testing.T) {
+	d := newDryRunChecks(readOnlyChecks{t: t, checks: []pdom.CheckResponse{
+		{ID: 1, Name: "cats", Hostname: "cats.example.com", Resolution: 1, Status: "up"},
+		{ID: 2, Name: "dogs", Hostname: "dogs.example.com", Resolution: 1, Status: "up"},
+	}})
+
+	r, err := d.Create(newPingCheck("pets", "pets.example.com", tpr.Spec{Resolution: 5}))
+	assert.Nil(t, err)
+	assert.Equal(t, -1, r.ID)
+	assert.Equal(t, tpr.CheckTypePing, r.Type.Name)
+
+	_, err = d.Update(1, newPingCheck("cats", "cats.example.com", tpr.Spec{Resolution: 5, Paused: true}))
+	assert.Nil(t, err)
+	_, err = d.Delete(2)
+	assert.Nil(t, err)
+
+	list, err := d.List()
+	assert.Nil(t, err)
+	checks := make(map[int]pdom.CheckResponse)
+	for _, r := range list {
+		checks[r.ID] = r
+	}
+	assert.Equal(t, 2, len(checks))
+	assert.Equal(t, "pets.example.com", checks[-1].Hostname)
+	assert.True(t, checkMatches(checks[1], "cats.example.com", tpr.Spec{Resolution: 5, Paused: true}))
+
+	_, err = d.Read(2)
+	assert.NotNil(t, err)
+	_, err = d.Delete(2)
+	assert.NotNil(t, err)
+
+	r, err = d.Read(-1)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, r.Resolution)
+}
+
+func TestPlannedChecks(t *testing.T) {
+	o := &Operator{config: DefaultConfig(), planned: newPlannedChecks()}
+	o.config.DryRunAnnotation = ""
+
+	ing := &v1beta1.Ingress{}
+	ing.Namespace, ing.Name = "default", "pets"
+	ing.Annotations = map[string]string{DefaultChecksAnnotation: `{"pets.example.com": 1}`}
+
+	// The checks annotation is used until the Ingress is synced.
+	checks, err := o.getChecks(ing)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"pets.example.com": 1}, checks)
+
+	assert.Nil(t, o.setChecksAnnotation(ing, map[string]int{}))
+	checks, err = o.getChecks(ing)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{}, checks)
+	assert.False(t, o.hasChecks(ing))
+	assert.Equal(t, `{"pets.example.com": 1}`, ing.Annotations[DefaultChecksAnnotation])
+
+	o.planned.forget("default/pets")
+	assert.True(t, o.hasChecks(ing))
+}
@@ -0,0 +1,92 @@
+package pingdom
+
+import (
+	"fmt"
+
+	"github.com/rossf7/pingdom-operator/pkg/tpr"
+
+	"k8s.io/client-go/kubernetes"
+	v1core "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/pkg/apis/extensions/v1beta1"
+	"k8s.io/client-go/tools/record"
+)
+
+const eventComponent = "pingdom-operator"
+
+// Reasons of the events recorded on Ingresses and Checks.
+const (
+	reasonCheckCreated       = "CheckCreated"
+	reasonCheckUpdated       = "CheckUpdated"
+	reasonCheckDeleted       = "CheckDeleted"
+	reasonCreateCheckFailed  = "CreateCheckFailed"
+	reasonUpdateCheckFailed  = "UpdateCheckFailed"
+	reasonDeleteCheckFailed  = "DeleteCheckFailed"
+	reasonDeleteChecksFailed = "DeleteChecksFailed"
+)
+
+// Returns a recorder posting events to the API server. In dry-run mode
+// events are only logged, as no check is changed.
+func newEventRecorder(kclient kubernetes.Interface, dryRun bool) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartLogging(log.Debugf)
+	if !dryRun {
+		broadcaster.StartRecordingToSink(&v1core.EventSinkImpl{Interface: kclient.CoreV1().Events("")})
+	}
+	return broadcaster.NewRecorder(v1.EventSource{Component: eventComponent})
+}
+
+// Records an event on the Ingress.
+func (o *Operator) recordEvent(ing *v1beta1.Ingress, eventType, reason, message string) {
+	o.recorder.Event(ingressReference(ing), eventType, reason, message)
+}
+
+// Records an event of a Pingdom check on the Ingress, unless it is nil,
+// and on the Check resource referenced by the Ingress if it exists.
+func (o *Operator) recordCheckEvent(ing *v1.ObjectReference, namespace, checkName, eventType, reason, messageFmt string, args ...interface{}) {
+	message := fmt.Sprintf(messageFmt, args...)
+	if ing != nil {
+		o.recorder.Event(ing, eventType, reason, message)
+	}
+
+	ni := o.informersFor(namespace)
+	if ni == nil || checkName == "" {
+		return
+	}
+	check, err := ni.checkLister.Checks(namespace).Get(checkName)
+	if err != nil {
+		return
+	}
+	if ing != nil {
+		message = fmt.Sprintf("Ingress %s: %s", ing.Name, message)
+	}
+	o.recorder.Event(checkReference(check), eventType, reason, message)
+}
+
+// The recorder can't build references of Ingresses without their type
+// meta, which is not set on objects of the informers.
+func ingressReference(ing *v1beta1.Ingress) *v1.ObjectReference {
+	return &v1.ObjectReference{
+		Kind:            "Ingress",
+		APIVersion:      "extensions/v1beta1",
+		Namespace:       ing.Namespace,
+		Name:            ing.Name,
+		UID:             ing.UID,
+		ResourceVersion: ing.ResourceVersion,
+	}
+}
+
+func checkReference(check *tpr.PingdomCheck) *v1.ObjectReference {
+	kind := check.Kind
+	if kind == "" {
+		kind = "Check"
+	}
+	return &v1.ObjectReference{
+		Kind:            kind,
+		APIVersion:      check.APIVersion,
+		Namespace:       check.Namespace,
+		Name:            check.Name,
+		UID:             check.UID,
+		ResourceVersion: check.ResourceVersion,
+	}
+}
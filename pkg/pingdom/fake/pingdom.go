@@ -0,0 +1,298 @@
+// Package fake implements the Pingdom checks API in memory for tests of the
+// operator, with optional failures and latency.
+package fake
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	pdom "github.com/russellcardullo/go-pingdom/pingdom"
+)
+
+// Operations of the checks API, used to inject failures and count calls.
+const (
+	OpList   = "List"
+	OpCreate = "Create"
+	OpRead   = "Read"
+	OpUpdate = "Update"
+	OpDelete = "Delete"
+)
+
+// Pingdom is an in-memory Pingdom checks API. It is safe for concurrent
+// use.
+type Pingdom struct {
+	mux      sync.Mutex
+	lastID   int
+	checks   map[int]pdom.CheckResponse
+	failures map[string]*failure
+	calls    map[string]int
+	latency  time.Duration
+}
+
+type failure struct {
+	err error
+	// Number of calls left to fail, negative fails all calls.
+	n int
+	// The call takes effect before failing.
+	committed bool
+}
+
+// New returns a fake Pingdom without checks.
+func New() *Pingdom {
+	return &Pingdom{
+		checks:   make(map[int]pdom.CheckResponse),
+		failures: make(map[string]*failure),
+		calls:    make(map[string]int),
+	}
+}
+
+// Fail makes the next n calls of the operation return err. A negative n
+// fails all calls until Fail is called again, n = 0 removes the failure.
+func (p *Pingdom) Fail(op string, n int, err error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	if n == 0 {
+		delete(p.failures, op)
+		return
+	}
+	p.failures[op] = &failure{err: err, n: n}
+}
+
+// FailCommitted makes the next n calls of the operation take effect and
+// then return err, like a gateway timing out after Pingdom made the change.
+// Only Create supports it.
+func (p *Pingdom) FailCommitted(op string, n int, err error) {
+	p.Fail(op, n, err)
+
+	p.mux.Lock()
+	if f, ok := p.failures[op]; ok {
+		f.committed = true
+	}
+	p.mux.Unlock()
+}
+
+// SetLatency delays every call by d.
+func (p *Pingdom) SetLatency(d time.Duration) {
+	p.mux.Lock()
+	p.latency = d
+	p.mux.Unlock()
+}
+
+// Add adds the check, e.g. created by hand, and returns its ID. The ID of
+// the check is used if set.
+func (p *Pingdom) Add(r pdom.CheckResponse) int {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	if r.ID == 0 {
+		p.lastID++
+		r.ID = p.lastID
+	} else if r.ID > p.lastID {
+		p.lastID = r.ID
+	}
+	p.checks[r.ID] = r
+	return r.ID
+}
+
+// Checks returns the checks by ID.
+func (p *Pingdom) Checks() map[int]pdom.CheckResponse {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	checks := make(map[int]pdom.CheckResponse, len(p.checks))
+	for id, r := range p.checks {
+		checks[id] = r
+	}
+	return checks
+}
+
+// Hosts returns the hosts of the checks, sorted.
+func (p *Pingdom) Hosts() []string {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	var hosts []string
+	for _, r := range p.checks {
+		hosts = append(hosts, r.Hostname)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// Calls returns the number of calls of the operation, including failed
+// calls.
+func (p *Pingdom) Calls(op string) int {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	return p.calls[op]
+}
+
+// call counts the call of the operation, waits for the latency and returns
+// the injected failure, if any, and whether the call takes effect first.
+func (p *Pingdom) call(op string) (error, bool) {
+	p.mux.Lock()
+	p.calls[op]++
+	latency := p.latency
+	var err error
+	var committed bool
+	if f, ok := p.failures[op]; ok {
+		err, committed = f.err, f.committed
+		if f.n > 0 {
+			f.n--
+			if f.n == 0 {
+				delete(p.failures, op)
+			}
+		}
+	}
+	p.mux.Unlock()
+
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+	return err, committed
+}
+
+func (p *Pingdom) List(params ...map[string]string) ([]pdom.CheckResponse, error) {
+	if err, _ := p.call(OpList); err != nil {
+		return nil, err
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	list := make([]pdom.CheckResponse, 0, len(p.checks))
+	for _, r := range p.checks {
+		list = append(list, r)
+	}
+	sort.Sort(byID(list))
+	return list, nil
+}
+
+func (p *Pingdom) Create(check pdom.Check) (*pdom.CheckResponse, error) {
+	failErr, committed := p.call(OpCreate)
+	if failErr != nil && !committed {
+		return nil, failErr
+	}
+	if err := check.Valid(); err != nil {
+		return nil, &pdom.PingdomError{StatusCode: http.StatusBadRequest, StatusDesc: "Bad Request", Message: err.Error()}
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.lastID++
+	r := checkResponse(p.lastID, check)
+	p.checks[r.ID] = r
+	if failErr != nil {
+		return nil, failErr
+	}
+	return &r, nil
+}
+
+func (p *Pingdom) Read(id int) (*pdom.CheckResponse, error) {
+	if err, _ := p.call(OpRead); err != nil {
+		return nil, err
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	r, ok := p.checks[id]
+	if !ok {
+		return nil, notFound(id)
+	}
+	return &r, nil
+}
+
+// Update replaces the check. The type of a check can't be changed, as in
+// Pingdom, but this is not enforced.
+func (p *Pingdom) Update(id int, check pdom.Check) (*pdom.PingdomResponse, error) {
+	if err, _ := p.call(OpUpdate); err != nil {
+		return nil, err
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	old, ok := p.checks[id]
+	if !ok {
+		return nil, notFound(id)
+	}
+	r := checkResponse(id, check)
+	if r.Type.Name == "" {
+		r.Type.Name = old.Type.Name
+	}
+	p.checks[id] = r
+	return &pdom.PingdomResponse{Message: "Modification of check was successful!"}, nil
+}
+
+func (p *Pingdom) Delete(id int) (*pdom.PingdomResponse, error) {
+	if err, _ := p.call(OpDelete); err != nil {
+		return nil, err
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	if _, ok := p.checks[id]; !ok {
+		return nil, notFound(id)
+	}
+	delete(p.checks, id)
+	return &pdom.PingdomResponse{Message: "Deletion of check was successful!"}, nil
+}
+
+func notFound(id int) error {
+	return &pdom.PingdomError{StatusCode: http.StatusNotFound, StatusDesc: "Not Found", Message: "Check " + strconv.Itoa(id) + " not found"}
+}
+
+// Returns the response of the check from its parameters. Only the
+// parameters returned when listing checks are kept, and the details of HTTP
+// checks returned when reading them.
+func checkResponse(id int, check pdom.Check) pdom.CheckResponse {
+	r := pdom.CheckResponse{ID: id, Status: "up"}
+	switch ck := check.(type) {
+	case *pdom.HttpCheck:
+		r.Name, r.Hostname, r.Resolution = ck.Name, ck.Hostname, ck.Resolution
+		r.Type.Name = "http"
+		r.Type.HTTP = &pdom.CheckResponseHTTPDetails{
+			Url:              ck.Url,
+			Encryption:       ck.Encryption,
+			Port:             ck.Port,
+			Username:         ck.Username,
+			Password:         ck.Password,
+			ShouldContain:    ck.ShouldContain,
+			ShouldNotContain: ck.ShouldNotContain,
+			RequestHeaders:   ck.RequestHeaders,
+		}
+		r.SendNotificationWhenDown, r.NotifyAgainEvery = ck.SendNotificationWhenDown, ck.NotifyAgainEvery
+		r.ContactIds, r.IntegrationIds = ck.ContactIds, ck.IntegrationIds
+		if ck.Paused {
+			r.Status = "paused"
+		}
+	case *pdom.PingCheck:
+		r.Name, r.Hostname, r.Resolution = ck.Name, ck.Hostname, ck.Resolution
+		r.Type.Name = "ping"
+		if ck.Paused {
+			r.Status = "paused"
+		}
+	default:
+		params := check.PostParams()
+		r.Name, r.Hostname, r.Type.Name = params["name"], params["host"], params["type"]
+		r.Resolution, _ = strconv.Atoi(params["resolution"])
+		if params["paused"] == "true" {
+			r.Status = "paused"
+		}
+	}
+	return r
+}
+
+type byID []pdom.CheckResponse
+
+func (s byID) Len() int           { return len(s) }
+func (s byID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byID) Less(i, j int) bool { return s[i].ID < s[j].ID }
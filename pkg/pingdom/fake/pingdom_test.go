@@ -0,0 +1,53 @@
+package fake
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	pdom "github.com/russellcardullo/go-pingdom/pingdom"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFail(t *testing.T) {
+	p := New()
+	p.Fail(OpCreate, 1, errors.New("boom"))
+
+	check := &pdom.HttpCheck{Name: "test", Hostname: "test.example.com", Resolution: 5}
+	_, err := p.Create(check)
+	assert.EqualError(t, err, "boom")
+
+	r, err := p.Create(check)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, r.ID)
+	assert.Equal(t, "http", r.Type.Name)
+	assert.Equal(t, 2, p.Calls(OpCreate))
+
+	p.Fail(OpRead, -1, errors.New("down"))
+	for i := 0; i < 3; i++ {
+		_, err = p.Read(r.ID)
+		assert.EqualError(t, err, "down")
+	}
+	p.Fail(OpRead, 0, nil)
+	_, err = p.Read(r.ID)
+	assert.Nil(t, err)
+}
+
+func TestNotFound(t *testing.T) {
+	p := New()
+
+	_, err := p.Delete(1)
+	perr, ok := err.(*pdom.PingdomError)
+	assert.True(t, ok)
+	assert.Equal(t, 404, perr.StatusCode)
+}
+
+func TestLatency(t *testing.T) {
+	p := New()
+	p.SetLatency(20 * time.Millisecond)
+
+	start := time.Now()
+	_, err := p.List()
+	assert.Nil(t, err)
+	assert.True(t, time.Since(start) >= 20*time.Millisecond)
+}
@@ -0,0 +1,108 @@
+package pingdom
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/client-go/pkg/apis/extensions/v1beta1"
+)
+
+// checkOwner identifies the Ingress a check was created for. It is stored
+// in the check name as "[<cluster ID>/<namespace>/<name>] <host>".
+type checkOwner struct {
+	ClusterID string
+	Namespace string
+	Name      string
+}
+
+func (o *Operator) owner(ing *v1beta1.Ingress) checkOwner {
+	return checkOwner{ClusterID: o.config.ClusterID, Namespace: ing.Namespace, Name: ing.Name}
+}
+
+// Returns the name of the check for the host.
+func (c checkOwner) checkName(host string) string {
+	return fmt.Sprintf("[%s/%s/%s] %s", c.ClusterID, c.Namespace, c.Name, host)
+}
+
+// Parses the owner from the check name. Returns false if the check was not
+// created by the operator.
+func parseCheckOwner(checkName string) (checkOwner, bool) {
+	if !strings.HasPrefix(checkName, "[") {
+		return checkOwner{}, false
+	}
+	end := strings.Index(checkName, "] ")
+	if end < 0 {
+		return checkOwner{}, false
+	}
+	parts := strings.Split(checkName[1:end], "/")
+	if len(parts) != 3 {
+		return checkOwner{}, false
+	}
+	return checkOwner{ClusterID: parts[0], Namespace: parts[1], Name: parts[2]}, true
+}
+
+// Deletes checks owned by this operator whose Ingress no longer exists,
+// e.g. because it was deleted while the operator was down.
+func (o *Operator) collectGarbage() {
+	logger := o.eventLogger("collectGarbage")
+	logger.Debug("Start")
+	defer logger.Debug("End")
+
+	existing, err := o.listChecks()
+	if err != nil {
+		logger.WithError(err).Error("Error listing checks")
+		return
+	}
+
+	for id, r := range existing {
+		owner, ok := parseCheckOwner(r.Name)
+		if !ok || owner.ClusterID != o.config.ClusterID {
+			continue
+		}
+		// Ingresses of namespaces which are not watched, or were only
+		// just added, are not in the informer stores.
+		ni := o.informersFor(owner.Namespace)
+		if ni == nil || !ni.ingInf.HasSynced() {
+			continue
+		}
+
+		key := owner.Namespace + "/" + owner.Name
+		_, exists, err := ni.ingInf.GetStore().GetByKey(key)
+		if err != nil {
+			logger.WithFields(logrus.Fields{"namespace": owner.Namespace, "ingress": owner.Name}).WithError(err).Error("Error getting ingress")
+			continue
+		}
+		if exists {
+			continue
+		}
+		// Checks of Ingresses deleted while running are deleted by workers.
+		o.deletedMux.Lock()
+		_, deleted := o.deleted[ingressKeyPrefix+key]
+		o.deletedMux.Unlock()
+		if deleted {
+			continue
+		}
+
+		checkLogger := logger.WithFields(logrus.Fields{
+			"namespace":  owner.Namespace,
+			"ingress":    owner.Name,
+			"host":       r.Hostname,
+			"check_id":   id,
+			"check_name": r.Name,
+		})
+		if o.config.GCDryRun {
+			checkLogger.Info("Dry run: would delete orphaned check")
+			continue
+		}
+
+		err = o.deleteCheck(id)
+		if err == nil {
+			o.checks.Forget(id)
+			checkLogger.Info("Deleted orphaned check")
+		} else {
+			checkLogger.WithError(err).Error("Error deleting orphaned check")
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package pingdom
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rossf7/pingdom-operator/pkg/pingdom/fake"
+)
+
+func TestCheckOwner(t *testing.T) {
+	owner := checkOwner{ClusterID: "prod", Namespace: "default", Name: "pets"}
+	name := owner.checkName("cat.example.com")
+	assert.Equal(t, "[prod/default/pets] cat.example.com", name)
+
+	parsed, ok := parseCheckOwner(name)
+	assert.True(t, ok)
+	assert.Equal(t, owner, parsed)
+}
+
+func TestParseCheckOwnerNotOwned(t *testing.T) {
+	for _, name := range []string{
+		"cat.example.com",
+		"[prod/pets] cat.example.com",
+		"[prod/default/pets]",
+	} {
+		_, ok := parseCheckOwner(name)
+		assert.False(t, ok, name)
+	}
+}
+
+func TestCollectGarbage(t *testing.T) {
+	config := DefaultConfig()
+	config.ClusterID = "prod"
+	h := newHarness(t, config)
+	defer h.stop()
+	h.createIngress(newIngress("default", "pets", "pets", "cats.example.com"))
+	h.sync()
+
+	h.pingdom.Add(newCheckResponse("[prod/default/gone] dogs.example.com", "dogs.example.com"))
+	h.pingdom.Add(newCheckResponse("[staging/default/gone] ants.example.com", "ants.example.com"))
+	h.pingdom.Add(newCheckResponse("bees.example.com", "bees.example.com"))
+	h.o.collectGarbage()
+
+	// Only the orphaned check of this cluster is deleted.
+	assert.Equal(t, []string{"ants.example.com", "bees.example.com", "cats.example.com"}, h.pingdom.Hosts())
+	assert.Equal(t, 1, h.pingdom.Calls(fake.OpDelete))
+}
+
+func TestCollectGarbageDryRun(t *testing.T) {
+	config := DefaultConfig()
+	config.ClusterID = "prod"
+	config.GCDryRun = true
+	h := newHarness(t, config)
+	defer h.stop()
+
+	h.pingdom.Add(newCheckResponse("[prod/default/gone] dogs.example.com", "dogs.example.com"))
+	h.o.collectGarbage()
+
+	assert.Equal(t, []string{"dogs.example.com"}, h.pingdom.Hosts())
+	assert.Equal(t, 0, h.pingdom.Calls(fake.OpDelete))
+}
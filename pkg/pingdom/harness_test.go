@@ -0,0 +1,342 @@
+package pingdom
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/rossf7/pingdom-operator/pkg/pingdom/fake"
+	"github.com/rossf7/pingdom-operator/pkg/tpr"
+
+	kfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/pkg/api/errors"
+	"k8s.io/client-go/pkg/api/unversioned"
+	"k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/pkg/apis/extensions/v1beta1"
+	"k8s.io/client-go/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+)
+
+// harness drives the operator with Ingresses of a fake clientset, Checks of
+// fakeChecks and a fake Pingdom. The fake clientset does not send watch
+// events, so the harness updates the informer stores and queues the changes
+// instead, and the queue is processed synchronously by sync. The harness
+// must be stopped by the test.
+type harness struct {
+	t       *testing.T
+	kclient *kfake.Clientset
+	checks  *fakeChecks
+	pingdom *fake.Pingdom
+	o       *Operator
+	events  *record.FakeRecorder
+	stopc   chan struct{}
+}
+
+func newHarness(t *testing.T, config Config) *harness {
+	h := &harness{
+		t:       t,
+		kclient: kfake.NewSimpleClientset(),
+		checks:  newFakeChecks(),
+		pingdom: fake.New(),
+		events:  record.NewFakeRecorder(100),
+		stopc:   make(chan struct{}),
+	}
+	config.APIQPS = 0
+	// Tests change checks in the fake directly.
+	config.ListCacheTTL = 0
+	h.o = New(config, h.kclient, h.checks, h.pingdom)
+	h.o.recorder = h.events
+
+	go h.o.RunInformers(h.stopc)
+	synced := func() bool { return h.o.informersSynced() == nil }
+	if !cache.WaitForCacheSync(h.stopc, synced) {
+		t.Fatal("informers not synced")
+	}
+	return h
+}
+
+func (h *harness) stop() {
+	close(h.stopc)
+	h.o.queue.ShutDown()
+}
+
+// recordedEvents returns the events recorded since the last call.
+func (h *harness) recordedEvents() []string {
+	var events []string
+	for {
+		select {
+		case e := <-h.events.Events:
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+}
+
+func (h *harness) informers(namespace string) *namespaceInformers {
+	ni := h.o.informersFor(namespace)
+	if ni == nil {
+		h.t.Fatalf("namespace %s is not watched", namespace)
+	}
+	return ni
+}
+
+func (h *harness) ingress(namespace, name string) *v1beta1.Ingress {
+	ing, err := h.kclient.ExtensionsV1beta1().Ingresses(namespace).Get(name)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	return ing
+}
+
+func (h *harness) createIngress(ing *v1beta1.Ingress) {
+	h.o.enqueueIngress(h.addIngress(ing))
+}
+
+// addIngress creates the Ingress without queueing it, like an Ingress
+// which existed before the operator started.
+func (h *harness) addIngress(ing *v1beta1.Ingress) *v1beta1.Ingress {
+	ing, err := h.kclient.ExtensionsV1beta1().Ingresses(ing.Namespace).Create(ing)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	h.informers(ing.Namespace).ingInf.GetStore().Add(ing)
+	return ing
+}
+
+func (h *harness) updateIngress(ing *v1beta1.Ingress) {
+	ing, err := h.kclient.ExtensionsV1beta1().Ingresses(ing.Namespace).Update(ing)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	h.informers(ing.Namespace).ingInf.GetStore().Update(ing)
+	h.o.enqueueIngress(ing)
+}
+
+// deleteIngress deletes the Ingress like the API server does: it is only
+// marked as deleted while it has finalizers.
+func (h *harness) deleteIngress(namespace, name string) {
+	ing := h.ingress(namespace, name)
+	if len(ing.Finalizers) > 0 {
+		now := unversioned.Now()
+		ing.DeletionTimestamp = &now
+		h.updateIngress(ing)
+		return
+	}
+
+	err := h.kclient.ExtensionsV1beta1().Ingresses(namespace).Delete(name, nil)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	h.informers(namespace).ingInf.GetStore().Delete(ing)
+	h.o.enqueueDeletedIngress(ing)
+}
+
+// createCheck creates the Check and queues it.
+func (h *harness) createCheck(check *tpr.PingdomCheck) {
+	check, err := h.checks.Checks(check.Namespace).Create(check)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	h.informers(check.Namespace).checkInf.GetStore().Add(check)
+	h.o.enqueueCheck(check)
+}
+
+func (h *harness) updateCheck(check *tpr.PingdomCheck) {
+	check, err := h.checks.Checks(check.Namespace).Update(check)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	h.informers(check.Namespace).checkInf.GetStore().Update(check)
+	h.o.enqueueCheck(check)
+}
+
+func (h *harness) deleteCheck(namespace, name string) {
+	check, err := h.checks.Checks(namespace).Get(name)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	if err := h.checks.Checks(namespace).Delete(name); err != nil {
+		h.t.Fatal(err)
+	}
+	h.informers(namespace).checkInf.GetStore().Delete(check)
+	h.o.enqueueCheck(check)
+}
+
+// resync queues all Ingresses like the informer resync.
+func (h *harness) resync() {
+	for _, ing := range h.o.listIngresses() {
+		h.o.enqueueIngress(ing)
+	}
+}
+
+// sync processes the queue until the operator converges. Failed keys are
+// not retried, they are processed again by resync.
+func (h *harness) sync() {
+	for i := 0; i < 10; i++ {
+		for h.o.queue.Len() > 0 {
+			h.o.processNextItem()
+		}
+		if !h.refresh() {
+			return
+		}
+	}
+	h.t.Fatal("operator did not converge")
+}
+
+// refresh updates the Ingress stores with the changes made by the
+// operator and queues the changed Ingresses. Ingresses marked as deleted
+// are deleted once they have no finalizers. Returns true if any Ingress
+// was queued.
+func (h *harness) refresh() bool {
+	list, err := h.kclient.ExtensionsV1beta1().Ingresses(v1.NamespaceAll).List(v1.ListOptions{})
+	if err != nil {
+		h.t.Fatal(err)
+	}
+
+	var changed bool
+	for i := range list.Items {
+		ing := &list.Items[i]
+		if ing.DeletionTimestamp != nil && len(ing.Finalizers) == 0 {
+			h.deleteIngress(ing.Namespace, ing.Name)
+			changed = true
+			continue
+		}
+
+		store := h.informers(ing.Namespace).ingInf.GetStore()
+		old, exists, err := store.Get(ing)
+		if err != nil {
+			h.t.Fatal(err)
+		}
+		if exists && reflect.DeepEqual(old, ing) {
+			continue
+		}
+		store.Update(ing)
+		h.o.enqueueIngress(ing)
+		changed = true
+	}
+	return changed
+}
+
+// fakeChecks stores Check resources in memory.
+type fakeChecks struct {
+	mux    sync.Mutex
+	checks map[string]*tpr.PingdomCheck
+}
+
+func newFakeChecks() *fakeChecks {
+	return &fakeChecks{checks: make(map[string]*tpr.PingdomCheck)}
+}
+
+func (f *fakeChecks) Checks(namespace string) tpr.CheckInterface {
+	return &fakeCheckClient{fakeChecks: f, namespace: namespace}
+}
+
+func (f *fakeChecks) get(namespace, name string) *tpr.PingdomCheck {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	check, ok := f.checks[namespace+"/"+name]
+	if !ok {
+		return nil
+	}
+	c := *check
+	return &c
+}
+
+type fakeCheckClient struct {
+	*fakeChecks
+	namespace string
+}
+
+func (c *fakeCheckClient) notFound(name string) error {
+	return errors.NewNotFound(unversioned.GroupResource{Group: tpr.DefaultGroup, Resource: "checks"}, name)
+}
+
+func (c *fakeCheckClient) Create(check *tpr.PingdomCheck) (*tpr.PingdomCheck, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	key := c.namespace + "/" + check.Name
+	if _, ok := c.checks[key]; ok {
+		return nil, errors.NewAlreadyExists(unversioned.GroupResource{Group: tpr.DefaultGroup, Resource: "checks"}, check.Name)
+	}
+	created := *check
+	created.Namespace = c.namespace
+	created.Generation = 1
+	c.checks[key] = &created
+	result := created
+	return &result, nil
+}
+
+func (c *fakeCheckClient) Update(check *tpr.PingdomCheck) (*tpr.PingdomCheck, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	old, ok := c.checks[c.namespace+"/"+check.Name]
+	if !ok {
+		return nil, c.notFound(check.Name)
+	}
+	updated := *check
+	updated.Status = old.Status
+	updated.Generation = old.Generation
+	if !reflect.DeepEqual(old.Spec, check.Spec) {
+		updated.Generation++
+	}
+	c.checks[c.namespace+"/"+check.Name] = &updated
+	result := updated
+	return &result, nil
+}
+
+func (c *fakeCheckClient) UpdateStatus(check *tpr.PingdomCheck) (*tpr.PingdomCheck, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	old, ok := c.checks[c.namespace+"/"+check.Name]
+	if !ok {
+		return nil, c.notFound(check.Name)
+	}
+	updated := *old
+	updated.Status = check.Status
+	c.checks[c.namespace+"/"+check.Name] = &updated
+	result := updated
+	return &result, nil
+}
+
+func (c *fakeCheckClient) Delete(name string) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if _, ok := c.checks[c.namespace+"/"+name]; !ok {
+		return c.notFound(name)
+	}
+	delete(c.checks, c.namespace+"/"+name)
+	return nil
+}
+
+func (c *fakeCheckClient) Get(name string) (*tpr.PingdomCheck, error) {
+	check := c.get(c.namespace, name)
+	if check == nil {
+		return nil, c.notFound(name)
+	}
+	return check, nil
+}
+
+func (c *fakeCheckClient) List(opts v1.ListOptions) (*tpr.PingdomCheckList, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	list := &tpr.PingdomCheckList{}
+	for _, check := range c.checks {
+		if c.namespace == v1.NamespaceAll || check.Namespace == c.namespace {
+			item := *check
+			list.Items = append(list.Items, &item)
+		}
+	}
+	return list, nil
+}
+
+func (c *fakeCheckClient) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	return watch.NewEmptyWatch(), nil
+}
@@ -0,0 +1,155 @@
+package pingdom
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	pdom "github.com/russellcardullo/go-pingdom/pingdom"
+)
+
+// How long Ready waits for listing the checks.
+const apiProbeTimeout = 10 * time.Second
+
+// apiHealth tracks the result of the last Pingdom API call.
+type apiHealth struct {
+	mux      sync.Mutex
+	lastCall time.Time
+	lastErr  error
+}
+
+// record stores the result of an API call. Client errors caused by the
+// request, e.g. reading a deleted check, don't make the API unhealthy.
+func (h *apiHealth) record(err error) {
+	if perr, ok := err.(*pdom.PingdomError); ok && !authError(err) && perr.StatusCode < 500 {
+		err = nil
+	}
+
+	h.mux.Lock()
+	h.lastCall = time.Now()
+	h.lastErr = err
+	h.mux.Unlock()
+}
+
+func (h *apiHealth) last() (time.Time, error) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	return h.lastCall, h.lastErr
+}
+
+// heartbeat tracks when the workers last finished processing a key, while
+// they are running.
+type heartbeat struct {
+	mux     sync.Mutex
+	running bool
+	last    time.Time
+}
+
+func (h *heartbeat) start() {
+	h.mux.Lock()
+	h.running = true
+	h.last = time.Now()
+	h.mux.Unlock()
+}
+
+func (h *heartbeat) stop() {
+	h.mux.Lock()
+	h.running = false
+	h.mux.Unlock()
+}
+
+func (h *heartbeat) beat() {
+	h.mux.Lock()
+	h.last = time.Now()
+	h.mux.Unlock()
+}
+
+// Returns how long ago the last key was processed, zero if the workers
+// are not running.
+func (h *heartbeat) age() time.Duration {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if !h.running {
+		return 0
+	}
+	return time.Since(h.last)
+}
+
+// Returns true if Pingdom rejected the credentials.
+func authError(err error) bool {
+	perr, ok := err.(*pdom.PingdomError)
+	return ok && (perr.StatusCode == http.StatusUnauthorized || perr.StatusCode == http.StatusForbidden)
+}
+
+// Ready returns an error if the informers have not synced yet or the last
+// Pingdom API call failed. If there was no call within the ready window,
+// e.g. on followers, the checks are listed to test the credentials. Rejected
+// credentials are tested on every call, so the operator becomes ready as
+// soon as they are replaced. The checks are listed once, without waiting
+// for the rate limit or retrying, so probes don't time out.
+func (o *Operator) Ready() error {
+	if err := o.informersSynced(); err != nil {
+		return err
+	}
+
+	lastCall, err := o.apiHealth.last()
+	if time.Since(lastCall) > o.config.APIReadyWindow || authError(err) {
+		err = o.probeAPI()
+	}
+	if authError(err) {
+		return fmt.Errorf("pingdom api: credentials rejected: %v", err)
+	}
+	if err != nil {
+		return fmt.Errorf("pingdom api: %v", err)
+	}
+	return nil
+}
+
+// probeAPI lists the checks with the probe client and returns the result,
+// or an error if it takes longer than apiProbeTimeout. A call timing out
+// finishes in the background, and is waited for by the next calls instead
+// of starting another one.
+func (o *Operator) probeAPI() error {
+	o.probeMux.Lock()
+	if o.probe == nil {
+		done := make(chan struct{})
+		o.probe = done
+		go func() {
+			start := time.Now()
+			_, err := o.probeClient.List()
+			o.observeAPI("listChecks", start, err)
+
+			o.probeMux.Lock()
+			o.probe = nil
+			o.probeMux.Unlock()
+			close(done)
+		}()
+	}
+	done := o.probe
+	o.probeMux.Unlock()
+
+	timeout := time.NewTimer(apiProbeTimeout)
+	defer timeout.Stop()
+	select {
+	case <-done:
+		_, err := o.apiHealth.last()
+		return err
+	case <-timeout.C:
+		return fmt.Errorf("listing checks timed out after %v", apiProbeTimeout)
+	}
+}
+
+// Healthy returns an error if the workers have not processed any key within
+// the heartbeat timeout, e.g. because they are stuck. A heartbeat key is
+// queued periodically, so idle workers stay healthy. Followers don't run
+// workers and are always healthy.
+func (o *Operator) Healthy() error {
+	if o.config.HeartbeatTimeout <= 0 {
+		return nil
+	}
+	if age := o.heartbeat.age(); age > o.config.HeartbeatTimeout {
+		return fmt.Errorf("workers have not processed the queue for %v", age-age%time.Second)
+	}
+	return nil
+}
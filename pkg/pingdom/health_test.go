@@ -0,0 +1,65 @@
+package pingdom
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	pdom "github.com/russellcardullo/go-pingdom/pingdom"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rossf7/pingdom-operator/pkg/pingdom/fake"
+)
+
+func TestAPIHealthRecord(t *testing.T) {
+	var h apiHealth
+
+	h.record(nil)
+	_, err := h.last()
+	assert.Nil(t, err)
+
+	h.record(&pdom.PingdomError{StatusCode: 404})
+	_, err = h.last()
+	assert.Nil(t, err)
+
+	h.record(&pdom.PingdomError{StatusCode: 401})
+	_, err = h.last()
+	assert.NotNil(t, err)
+
+	h.record(&pdom.PingdomError{StatusCode: 503})
+	_, err = h.last()
+	assert.NotNil(t, err)
+
+	h.record(errors.New("connection refused"))
+	lastCall, err := h.last()
+	assert.NotNil(t, err)
+	assert.False(t, lastCall.IsZero())
+}
+
+func TestReadyWithoutRetries(t *testing.T) {
+	h := newHarness(t, DefaultConfig())
+	defer h.stop()
+
+	h.pingdom.Fail(fake.OpList, -1, &pdom.PingdomError{StatusCode: 503, StatusDesc: "Service Unavailable"})
+	assert.EqualError(t, h.o.Ready(), "pingdom api: 503 Service Unavailable: ")
+	assert.Equal(t, 1, h.pingdom.Calls(fake.OpList))
+}
+
+func TestHealthy(t *testing.T) {
+	h := newHarness(t, DefaultConfig())
+	defer h.stop()
+
+	// Followers don't run workers.
+	h.o.heartbeat.last = time.Now().Add(-time.Hour)
+	assert.Nil(t, h.o.Healthy())
+
+	h.o.heartbeat.start()
+	assert.Nil(t, h.o.Healthy())
+
+	h.o.heartbeat.last = time.Now().Add(-time.Hour)
+	assert.EqualError(t, h.o.Healthy(), "workers have not processed the queue for 1h0m0s")
+
+	h.o.queue.Add(heartbeatKey)
+	h.sync()
+	assert.Nil(t, h.o.Healthy())
+}
@@ -0,0 +1,100 @@
+package pingdom
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricsNamespace = "pingdom_operator"
+
+var (
+	apiRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "api_requests_total",
+		Help:      "Pingdom API requests by operation and result.",
+	}, []string{"operation", "result"})
+
+	apiRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "api_request_duration_seconds",
+		Help:      "Duration of Pingdom API requests by operation.",
+	}, []string{"operation"})
+
+	events = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "events_total",
+		Help:      "Informer events by kind of resource and event type.",
+	}, []string{"kind", "type"})
+
+	syncs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "syncs_total",
+		Help:      "Processed queue keys by kind of resource and result. Failed syncs are retried.",
+	}, []string{"kind", "result"})
+
+	syncDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "sync_duration_seconds",
+		Help:      "Duration of processing queue keys by kind of resource.",
+	}, []string{"kind"})
+
+	queueDepthDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "queue_depth"),
+		"Number of keys waiting in the work queue.",
+		nil, nil,
+	)
+
+	managedChecksDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "managed_checks"),
+		"Number of Pingdom checks using a Check spec, by namespace and name of the Check.",
+		[]string{"namespace", "check"}, nil,
+	)
+)
+
+func init() {
+	prometheus.MustRegister(apiRequests, apiRequestDuration, events, syncs, syncDuration)
+}
+
+// Label values of the kind of resource.
+const (
+	kindIngress = "ingress"
+	kindCheck   = "check"
+)
+
+// observeAPI records a Pingdom API request started at start.
+func (o *Operator) observeAPI(operation string, start time.Time, err error) {
+	o.apiHealth.record(err)
+	apiRequestDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	apiRequests.WithLabelValues(operation, result(err)).Inc()
+}
+
+// observeSync records processing a queue key started at start.
+func observeSync(kind string, start time.Time, err error) {
+	syncDuration.WithLabelValues(kind).Observe(time.Since(start).Seconds())
+	syncs.WithLabelValues(kind, result(err)).Inc()
+}
+
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
+
+// collector reports the state of the operator when scraped.
+type collector struct {
+	o *Operator
+}
+
+func (c collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- queueDepthDesc
+	ch <- managedChecksDesc
+}
+
+func (c collector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(c.o.queue.Len()))
+	for key, n := range c.o.checks.Counts() {
+		ch <- prometheus.MustNewConstMetric(managedChecksDesc, prometheus.GaugeValue, float64(n), key.namespace, key.name)
+	}
+}
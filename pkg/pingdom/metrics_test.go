@@ -0,0 +1,44 @@
+package pingdom
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rossf7/pingdom-operator/pkg/util"
+)
+
+func TestCollector(t *testing.T) {
+	o := &Operator{
+		queue:  util.NewWorkQueue(retryBaseDelay, retryMaxDelay),
+		checks: newPingdomChecks(),
+	}
+	o.queue.Add("ingress/default/pets")
+	o.queue.Add("check/default/pets")
+	o.checks.Add("default", "pets", 1, 2)
+	o.checks.Add("default", "ants", 3)
+	o.checks.Add("team-a", "pets", 4)
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collector{o: o})
+	families, err := reg.Gather()
+	assert.Nil(t, err)
+
+	values := make(map[string]float64)
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			name := f.GetName()
+			for _, l := range m.GetLabel() {
+				name += "/" + l.GetValue()
+			}
+			values[name] = m.GetGauge().GetValue()
+		}
+	}
+	assert.Equal(t, map[string]float64{
+		"pingdom_operator_queue_depth":                 2,
+		"pingdom_operator_managed_checks/ants/default": 1,
+		"pingdom_operator_managed_checks/pets/default": 2,
+		"pingdom_operator_managed_checks/pets/team-a":  1,
+	}, values)
+}
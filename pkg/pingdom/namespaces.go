@@ -0,0 +1,233 @@
+package pingdom
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/rossf7/pingdom-operator/pkg/tpr"
+
+	"k8s.io/client-go/pkg/api"
+	"k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/pkg/apis/extensions/v1beta1"
+	"k8s.io/client-go/pkg/runtime"
+	"k8s.io/client-go/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// namespaceInformers are the Ingress and Check informers of a watched
+// namespace, or of all namespaces if the namespace is empty.
+type namespaceInformers struct {
+	ingInf      cache.SharedIndexInformer
+	checkInf    cache.SharedIndexInformer
+	checkLister tpr.CheckLister
+	stopc       chan struct{}
+}
+
+func (ni *namespaceInformers) run() {
+	go ni.ingInf.Run(ni.stopc)
+	go ni.checkInf.Run(ni.stopc)
+}
+
+func (ni *namespaceInformers) stop() {
+	close(ni.stopc)
+}
+
+// newNamespaceInformers creates the informers of the namespace queueing
+// their events.
+func (o *Operator) newNamespaceInformers(namespace string) *namespaceInformers {
+	ingress := o.kclient.ExtensionsV1beta1().Ingresses(namespace)
+	ni := &namespaceInformers{
+		ingInf: cache.NewSharedIndexInformer(
+			&cache.ListWatch{
+				ListFunc: func(options api.ListOptions) (runtime.Object, error) {
+					var v1Options v1.ListOptions
+					v1.Convert_api_ListOptions_To_v1_ListOptions(&options, &v1Options, nil)
+					return ingress.List(v1Options)
+				},
+				WatchFunc: func(options api.ListOptions) (watch.Interface, error) {
+					var v1Options v1.ListOptions
+					v1.Convert_api_ListOptions_To_v1_ListOptions(&options, &v1Options, nil)
+					return ingress.Watch(v1Options)
+				},
+			},
+			&v1beta1.Ingress{}, o.config.ResyncPeriod, cache.Indexers{},
+		),
+		checkInf: tpr.NewCheckInformer(o.checkClient, namespace),
+		stopc:    make(chan struct{}),
+	}
+	ni.checkLister = tpr.NewCheckLister(ni.checkInf.GetIndexer())
+
+	ni.checkInf.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			events.WithLabelValues(kindCheck, "add").Inc()
+			o.enqueueCheck(obj)
+		},
+		UpdateFunc: func(old, new interface{}) {
+			events.WithLabelValues(kindCheck, "update").Inc()
+			// Status updates must not trigger another sync.
+			if !reflect.DeepEqual(old.(*tpr.PingdomCheck).Spec, new.(*tpr.PingdomCheck).Spec) {
+				o.enqueueCheck(new)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			events.WithLabelValues(kindCheck, "delete").Inc()
+			o.enqueueCheck(obj)
+		},
+	})
+
+	ni.ingInf.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			events.WithLabelValues(kindIngress, "add").Inc()
+			o.enqueueIngress(obj)
+		},
+		UpdateFunc: func(old, new interface{}) {
+			events.WithLabelValues(kindIngress, "update").Inc()
+			o.enqueueIngress(new)
+		},
+		DeleteFunc: func(obj interface{}) {
+			events.WithLabelValues(kindIngress, "delete").Inc()
+			o.enqueueDeletedIngress(obj)
+		},
+	})
+
+	return ni
+}
+
+// newNamespaceInformer returns an informer of the namespaces matching the
+// label selector, which starts and stops watching them as they come and go.
+// Namespaces whose labels stop matching are deleted from the informer.
+func (o *Operator) newNamespaceInformer(selector string) cache.SharedIndexInformer {
+	namespaces := o.kclient.CoreV1().Namespaces()
+	inf := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options api.ListOptions) (runtime.Object, error) {
+				var v1Options v1.ListOptions
+				v1.Convert_api_ListOptions_To_v1_ListOptions(&options, &v1Options, nil)
+				v1Options.LabelSelector = selector
+				return namespaces.List(v1Options)
+			},
+			WatchFunc: func(options api.ListOptions) (watch.Interface, error) {
+				var v1Options v1.ListOptions
+				v1.Convert_api_ListOptions_To_v1_ListOptions(&options, &v1Options, nil)
+				v1Options.LabelSelector = selector
+				return namespaces.Watch(v1Options)
+			},
+		},
+		&v1.Namespace{}, 0, cache.Indexers{},
+	)
+
+	inf.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			o.watchNamespace(obj.(*v1.Namespace).Name)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if ns, ok := obj.(*v1.Namespace); ok {
+				o.unwatchNamespace(ns.Name)
+			}
+		},
+	})
+	return inf
+}
+
+// watchNamespace creates the informers of the namespace, running them if
+// the informers of the operator are running.
+func (o *Operator) watchNamespace(namespace string) {
+	o.nsMux.Lock()
+	defer o.nsMux.Unlock()
+
+	if _, ok := o.nsInfs[namespace]; ok {
+		return
+	}
+	ni := o.newNamespaceInformers(namespace)
+	o.nsInfs[namespace] = ni
+	if o.running {
+		ni.run()
+	}
+	log.WithField("namespace", namespace).Info("Watching namespace")
+}
+
+// unwatchNamespace stops the informers of the namespace. Its checks are
+// kept, and garbage collection skips them, until the namespace is watched
+// again.
+func (o *Operator) unwatchNamespace(namespace string) {
+	o.nsMux.Lock()
+	defer o.nsMux.Unlock()
+
+	ni, ok := o.nsInfs[namespace]
+	if !ok {
+		return
+	}
+	delete(o.nsInfs, namespace)
+	if o.running {
+		ni.stop()
+	}
+	log.WithField("namespace", namespace).Info("Stopped watching namespace")
+}
+
+// informersFor returns the informers watching the namespace, nil if the
+// namespace is not watched.
+func (o *Operator) informersFor(namespace string) *namespaceInformers {
+	o.nsMux.RLock()
+	defer o.nsMux.RUnlock()
+
+	if ni, ok := o.nsInfs[v1.NamespaceAll]; ok {
+		return ni
+	}
+	return o.nsInfs[namespace]
+}
+
+// informersSynced returns an error if any informer has not synced yet.
+func (o *Operator) informersSynced() error {
+	if o.nsInf != nil && !o.nsInf.HasSynced() {
+		return fmt.Errorf("namespace informer not synced")
+	}
+
+	o.nsMux.RLock()
+	defer o.nsMux.RUnlock()
+
+	for namespace, ni := range o.nsInfs {
+		if !ni.ingInf.HasSynced() {
+			return fmt.Errorf("ingress informer of namespace %q not synced", namespace)
+		}
+		if !ni.checkInf.HasSynced() {
+			return fmt.Errorf("check informer of namespace %q not synced", namespace)
+		}
+	}
+	return nil
+}
+
+// getIngress returns the Ingress with the key from the informer of its
+// namespace.
+func (o *Operator) getIngress(key string) (*v1beta1.Ingress, bool, error) {
+	namespace, _, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil, false, err
+	}
+	ni := o.informersFor(namespace)
+	if ni == nil {
+		return nil, false, nil
+	}
+
+	obj, exists, err := ni.ingInf.GetStore().GetByKey(key)
+	if err != nil || !exists {
+		return nil, false, err
+	}
+	return obj.(*v1beta1.Ingress), true, nil
+}
+
+// listIngresses returns the Ingresses of all watched namespaces.
+func (o *Operator) listIngresses() []*v1beta1.Ingress {
+	o.nsMux.RLock()
+	defer o.nsMux.RUnlock()
+
+	var ingresses []*v1beta1.Ingress
+	for _, ni := range o.nsInfs {
+		for _, obj := range ni.ingInf.GetStore().List() {
+			ingresses = append(ingresses, obj.(*v1beta1.Ingress))
+		}
+	}
+	return ingresses
+}
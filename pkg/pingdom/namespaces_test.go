@@ -0,0 +1,70 @@
+package pingdom
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rossf7/pingdom-operator/pkg/tpr"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/pkg/apis/extensions/v1beta1"
+	"k8s.io/client-go/rest"
+)
+
+func newNamespacesOperator(t *testing.T) *Operator {
+	// The informers are never run, the clients are not used.
+	kclient, err := kubernetes.NewForConfig(&rest.Config{Host: "localhost:0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Operator{
+		kclient:     kclient,
+		checkClient: tpr.NewClient(kclient, tpr.DefaultConfig()),
+		config:      DefaultConfig(),
+		nsInfs:      make(map[string]*namespaceInformers),
+	}
+}
+
+func TestWatchNamespaces(t *testing.T) {
+	o := newNamespacesOperator(t)
+	o.watchNamespace("team-a")
+	o.watchNamespace("team-b")
+
+	ing := &v1beta1.Ingress{}
+	ing.Namespace, ing.Name = "team-a", "pets"
+	o.informersFor("team-a").ingInf.GetStore().Add(ing)
+
+	assert.NotNil(t, o.informersFor("team-b"))
+	assert.Nil(t, o.informersFor("team-c"))
+
+	got, exists, err := o.getIngress("team-a/pets")
+	assert.Nil(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, ing, got)
+	assert.Equal(t, 1, len(o.listIngresses()))
+
+	_, exists, err = o.getIngress("team-c/pets")
+	assert.Nil(t, err)
+	assert.False(t, exists)
+
+	o.unwatchNamespace("team-a")
+	assert.Nil(t, o.informersFor("team-a"))
+	_, exists, err = o.getIngress("team-a/pets")
+	assert.Nil(t, err)
+	assert.False(t, exists)
+	assert.Equal(t, 0, len(o.listIngresses()))
+
+	// Checks of namespaces which are not watched get the default spec.
+	assert.Equal(t, o.config.DefaultCheckSpec, o.checkSpec("team-a", "pets"))
+}
+
+func TestWatchAllNamespaces(t *testing.T) {
+	o := newNamespacesOperator(t)
+	o.watchNamespace(v1.NamespaceAll)
+
+	assert.NotNil(t, o.informersFor("team-a"))
+	assert.NotNil(t, o.informersFor("team-b"))
+	assert.NotNil(t, o.informersSynced())
+}
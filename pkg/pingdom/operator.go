@@ -3,247 +3,766 @@ package pingdom
 import (
 	"encoding/json"
 	"fmt"
-	"os"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
-	"github.com/op/go-logging"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rossf7/pingdom-operator/pkg/tpr"
-	pdom "github.com/russellcardullo/go-pingdom/pingdom"
+	"github.com/rossf7/pingdom-operator/pkg/util"
+	"github.com/sirupsen/logrus"
 
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/pkg/api"
+	"k8s.io/client-go/pkg/api/errors"
 	"k8s.io/client-go/pkg/api/v1"
 	"k8s.io/client-go/pkg/apis/extensions/v1beta1"
-	"k8s.io/client-go/pkg/runtime"
-	"k8s.io/client-go/pkg/watch"
+	utilerrors "k8s.io/client-go/pkg/util/errors"
+	"k8s.io/client-go/pkg/util/wait"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 )
 
 var (
-	log = logging.MustGetLogger("pingdom")
+	log = logrus.WithField("component", "pingdom")
 )
 
 const (
-	pingdomAnnotation = "monitoring.rossfairbanks.com/pingdom"
-	checksAnnotation  = "monitoring.rossfairbanks.com/pingdom_checks"
-	resyncPeriod      = 5 * time.Minute
+	// Default annotation keys of the Check name and of the created checks.
+	DefaultAnnotation       = "monitoring.rossfairbanks.com/pingdom"
+	DefaultChecksAnnotation = "monitoring.rossfairbanks.com/pingdom_checks"
+
+	DefaultResyncPeriod = 5 * time.Minute
+
+	// DefaultClusterID is the cluster ID of checks when none is set. It may
+	// be shared by several clusters, so their checks are not garbage
+	// collected unless GCDryRun is set.
+	DefaultClusterID = "default"
+	// DefaultGCPeriod is the interval of garbage collection once a cluster
+	// ID is set.
+	DefaultGCPeriod = time.Hour
+
+	// Finalizer set on annotated Ingresses so they are not removed before
+	// their checks are deleted.
+	checksFinalizer = "monitoring.rossfairbanks.com/pingdom-checks"
+
+	// Work queue keys are prefixed with the kind of the object.
+	ingressKeyPrefix   = "ingress/"
+	checkSpecKeyPrefix = "check/"
+	// Key queued periodically so idle workers record a heartbeat.
+	heartbeatKey    = "heartbeat"
+	heartbeatPeriod = 30 * time.Second
+
+	// Backoff of failed keys and the number of retries before a key is
+	// dropped. Dropped Ingresses are retried on the next resync, deleted
+	// Ingresses by enqueueDeleted.
+	retryBaseDelay = 5 * time.Second
+	retryMaxDelay  = 5 * time.Minute
+	maxRetries     = 10
+
+	// Number of failed attempts to delete the checks of an Ingress being
+	// deleted before a warning event is recorded.
+	finalizeEventRetries = 3
 )
 
+// Config of the operator.
+type Config struct {
+	// Namespaces to watch. All namespaces are watched if it is empty and
+	// there is no NamespaceSelector.
+	Namespaces []string
+	// Label selector of the namespaces to watch. Namespaces are watched
+	// while their labels match.
+	NamespaceSelector string
+	// Number of workers processing Ingress and Check events.
+	Workers int
+	// ClusterID is added to the name of every created check to tell which
+	// checks are owned by this operator. It must be unique per Pingdom
+	// account.
+	ClusterID string
+	// Interval of deleting checks whose Ingress no longer exists. Zero
+	// disables garbage collection, which also only runs as a dry run with
+	// the DefaultClusterID.
+	GCPeriod time.Duration
+	// GCDryRun only logs the checks garbage collection would delete.
+	GCDryRun bool
+	// APIReadyWindow is how long the result of the last Pingdom API call
+	// is used by Ready before calling the API again.
+	APIReadyWindow time.Duration
+	// Pingdom API requests per second and burst. Zero disables the limit.
+	APIQPS   float32
+	APIBurst int
+	// Retries of Pingdom API requests failing with 429 or 5xx responses.
+	APIRetries int
+	// ListCacheTTL is how long the list of checks is reused by reconciles
+	// before listing them again. Zero disables the cache.
+	ListCacheTTL time.Duration
+	// Interval of reconciling all Ingresses.
+	ResyncPeriod time.Duration
+	// HeartbeatTimeout is how long the workers may not process any key
+	// before Healthy fails. Zero disables the check.
+	HeartbeatTimeout time.Duration
+
+	// Annotation of Ingresses naming the Check resource to use.
+	Annotation string
+	// Annotation of Ingresses listing the created checks.
+	ChecksAnnotation string
+	// Spec of checks of Ingresses without a Check resource.
+	DefaultCheckSpec tpr.Spec
+
+	// DryRun only logs the checks which would be created, updated and
+	// deleted. The planned checks are set in the DryRunAnnotation of
+	// Ingresses, unless it is empty. The checks annotation and the
+	// finalizer are left as they are.
+	DryRun           bool
+	DryRunAnnotation string
+}
+
+// DefaultConfig returns the config with the default settings.
+func DefaultConfig() Config {
+	return Config{
+		Workers:          2,
+		ClusterID:        DefaultClusterID,
+		APIReadyWindow:   5 * time.Minute,
+		APIQPS:           5,
+		APIBurst:         10,
+		APIRetries:       5,
+		ListCacheTTL:     time.Minute,
+		ResyncPeriod:     DefaultResyncPeriod,
+		HeartbeatTimeout: 10 * time.Minute,
+		Annotation:       DefaultAnnotation,
+		ChecksAnnotation: DefaultChecksAnnotation,
+		DefaultCheckSpec: defaultCheckSpec,
+		DryRunAnnotation: DefaultDryRunAnnotation,
+	}
+}
+
 type Operator struct {
-	kclient *kubernetes.Clientset
-	pclient *pdom.Client
-	store   *tpr.Store
-	eventc  chan interface{}
+	kclient     kubernetes.Interface
+	pclient     ChecksAPI
+	checkClient tpr.CheckGetter
+	queue       *util.WorkQueue
+	recorder    record.EventRecorder
+	config      Config
 
 	checks *pingdomChecks
+	// Checks of Ingresses planned in dry-run mode, nil otherwise.
+	planned *plannedChecks
+
+	// Checks of deleted Ingresses by key, until they are deleted.
+	deletedMux *sync.Mutex
+	deleted    map[string]deletedIngress
 
 	eventCnt uint64
 
-	ingInf cache.SharedIndexInformer
+	// Informers by watched namespace. They are started when added if the
+	// informers of the operator are running.
+	nsMux   sync.RWMutex
+	nsInfs  map[string]*namespaceInformers
+	running bool
+	// Informer of the namespaces matching the namespace selector, nil if
+	// the watched namespaces are fixed.
+	nsInf cache.SharedIndexInformer
+
+	apiHealth apiHealth
+	heartbeat heartbeat
+	// Client and running call of Ready testing the API.
+	probeClient ChecksAPI
+	probeMux    sync.Mutex
+	probe       chan struct{}
 }
 
-// New creates a new controller.
-func New(namespace string, kclient *kubernetes.Clientset, store *tpr.Store) *Operator {
-	pclient := pdom.NewClient(os.Getenv("PINGDOM_USER"), os.Getenv("PINGDOM_PASSWORD"), os.Getenv("PINGDOM_API_KEY"))
+type deletedIngress struct {
+	ref       *v1.ObjectReference
+	checkName string
+	checks    map[string]int
+}
+
+// New creates a new controller managing checks with the Pingdom API.
+func New(config Config, kclient kubernetes.Interface, checkClient tpr.CheckGetter, pclient ChecksAPI) *Operator {
+	// Ready tests the API without the rate limit and retries, which may
+	// delay calls for minutes.
+	probeClient := pclient
+	pclient = newRetryingChecks(pclient, config.APIQPS, config.APIBurst, config.APIRetries)
+
+	var planned *plannedChecks
+	if config.DryRun {
+		log.Warning("Dry run: Pingdom checks are not changed")
+		pclient = newDryRunChecks(pclient)
+		planned = newPlannedChecks()
+	}
+	if config.ListCacheTTL > 0 {
+		pclient = newCachedChecks(pclient, config.ListCacheTTL)
+	}
 
 	c := &Operator{
-		kclient: kclient,
-		pclient: pclient,
-		store:   store,
-		eventc:  make(chan interface{}),
-		checks:  newPingdomChecks(),
-	}
-
-	ingress := kclient.Ingresses(namespace)
-
-	c.ingInf = cache.NewSharedIndexInformer(
-		&cache.ListWatch{
-			ListFunc: func(options api.ListOptions) (runtime.Object, error) {
-				var v1Options v1.ListOptions
-				v1.Convert_api_ListOptions_To_v1_ListOptions(&options, &v1Options, nil)
-				return ingress.List(v1Options)
-			},
-			WatchFunc: func(options api.ListOptions) (watch.Interface, error) {
-				var v1Options v1.ListOptions
-				v1.Convert_api_ListOptions_To_v1_ListOptions(&options, &v1Options, nil)
-				return ingress.Watch(v1Options)
-			},
-		},
-		&v1beta1.Ingress{}, resyncPeriod, cache.Indexers{},
-	)
-
-	c.store.Handler = tpr.StoreEventHandlerFuncs{
-		SetFunc: func(namespace, name string, spec tpr.Spec) {
-			c.eventc <- setCheckSpecEvent{Namespace: namespace, Name: name, Check: spec}
-		},
-		DeleteFunc: func(namespace, name string, spec tpr.Spec) {
-			c.eventc <- deleteCheckSpecEvent{Namespace: namespace, Name: name, Check: spec}
-		},
-	}
-
-	c.ingInf.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc: func(obj interface{}) {
-			c.eventc <- addIngressEvent{obj: obj}
-		},
-		UpdateFunc: func(old, new interface{}) {
-			c.eventc <- updateIngressEvent{old: old, new: new}
-		},
-		DeleteFunc: func(obj interface{}) {
-			c.eventc <- deleteIngressEvent{obj: obj}
-		},
-	})
+		kclient:     kclient,
+		pclient:     pclient,
+		probeClient: probeClient,
+		checkClient: checkClient,
+		queue:       util.NewWorkQueue(retryBaseDelay, retryMaxDelay),
+		recorder:    newEventRecorder(kclient, config.DryRun),
+		config:      config,
+		checks:      newPingdomChecks(),
+		planned:     planned,
+		deletedMux:  new(sync.Mutex),
+		deleted:     make(map[string]deletedIngress),
+		nsInfs:      make(map[string]*namespaceInformers),
+	}
+
+	switch {
+	case len(config.Namespaces) > 0:
+		for _, namespace := range config.Namespaces {
+			c.nsInfs[namespace] = c.newNamespaceInformers(namespace)
+		}
+	case config.NamespaceSelector != "":
+		c.nsInf = c.newNamespaceInformer(config.NamespaceSelector)
+	default:
+		c.nsInfs[v1.NamespaceAll] = c.newNamespaceInformers(v1.NamespaceAll)
+	}
+
+	// Only the collector of the first operator is registered, e.g. in
+	// tests creating several operators.
+	if err := prometheus.Register(collector{o: c}); err != nil {
+		if _, ok := err.(prometheus.AlreadyRegisteredError); !ok {
+			log.WithError(err).Error("Error registering metrics")
+		}
+	}
 
 	return c
 }
 
-// Run the controller.
+// RunInformers runs the informers of the watched namespaces until stopc is
+// closed. It is run by all replicas so followers have a warm cache when
+// they become leader.
+func (o *Operator) RunInformers(stopc <-chan struct{}) error {
+	o.nsMux.Lock()
+	o.running = true
+	for _, ni := range o.nsInfs {
+		ni.run()
+	}
+	o.nsMux.Unlock()
+
+	if o.nsInf != nil {
+		go o.nsInf.Run(stopc)
+	}
+	<-stopc
+
+	o.nsMux.Lock()
+	o.running = false
+	for _, ni := range o.nsInfs {
+		ni.stop()
+	}
+	o.nsMux.Unlock()
+	return nil
+}
+
+// Run the controller. Only the leader runs it, after RunInformers was
+// started. It returns once the workers finished their current key and the
+// periodic tasks stopped, so another leader doesn't change the same checks.
 func (o *Operator) Run(stopc <-chan struct{}) error {
-	go o.ingInf.Run(stopc)
-	go o.run()
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer o.queue.ShutDown()
+	until := func(f func(), period time.Duration) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			wait.Until(f, period, stopc)
+		}()
+	}
+
+	// The checks registry must be rebuilt before any Check spec events
+	// are processed, otherwise they would not update existing checks.
+	if !cache.WaitForCacheSync(stopc, func() bool { return o.informersSynced() == nil }) {
+		return nil
+	}
+	o.rebuildChecks()
+
+	o.heartbeat.start()
+	defer o.heartbeat.stop()
+	until(func() { o.queue.Add(heartbeatKey) }, heartbeatPeriod)
+
+	for i := 0; i < o.config.Workers; i++ {
+		until(func() { o.worker(stopc) }, time.Second)
+	}
+
+	if o.config.ResyncPeriod > 0 {
+		until(o.enqueueDeleted, o.config.ResyncPeriod)
+	}
+
+	if o.config.GCPeriod > 0 && o.config.ClusterID == DefaultClusterID && !o.config.GCDryRun {
+		log.Warning("Garbage collection disabled, the default cluster ID may be shared by other clusters")
+	} else if o.config.GCPeriod > 0 {
+		until(o.collectGarbage, o.config.GCPeriod)
+	}
 
 	<-stopc
-	close(o.eventc)
 	return nil
 }
 
-func (o *Operator) run() {
-	for e := range o.eventc {
-		switch e := e.(type) {
-		case addIngressEvent:
-			o.handleAddIngress(e.Ingress())
-		case deleteIngressEvent:
-			o.handleDeleteIngress(e.Ingress())
-		case updateIngressEvent:
-			o.handleUpdateIngress(e.OldIngress(), e.NewIngress())
-		case setCheckSpecEvent:
-			o.handleSetCheckSpec(e.Namespace, e.Name, e.Check)
-		case deleteCheckSpecEvent:
-			o.handleDeleteCheckSpec(e.Namespace, e.Name, e.Check)
-		default:
-			log.Error("Unhandled event: %+v", e)
-		}
+func (o *Operator) enqueueIngress(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting key of %+v", obj)
+		return
+	}
+	o.queue.Add(ingressKeyPrefix + key)
+}
+
+func (o *Operator) enqueueCheck(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting key of %+v", obj)
+		return
 	}
+	o.queue.Add(checkSpecKeyPrefix + key)
 }
 
-// Create Pingdom checks if the ingress has the annotation.
-func (o *Operator) handleAddIngress(ing *v1beta1.Ingress) {
-	checkName, ok := annotation(ing)
+// Keeps the checks of the deleted Ingress, it is gone from the informer
+// store when the key is processed.
+func (o *Operator) enqueueDeletedIngress(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	ing, ok := obj.(*v1beta1.Ingress)
+	if !ok {
+		log.Errorf("Error deleting unknown object %+v", obj)
+		return
+	}
+
+	checkName, ok := o.annotation(ing)
 	if !ok {
 		return
 	}
-	if hasChecks(ing) {
+	checks, err := o.getChecks(ing)
+	if err != nil {
+		log.WithFields(ingressFields(ing)).WithError(err).Error("Error deleting ingress")
 		return
 	}
 
-	logp := fmt.Sprintf("AddIngress[%d]", atomic.AddUint64(&o.eventCnt, 1))
-	log.Debugf("%s obj=%s", logp, ing.Name)
-	defer log.Debugf("%s end", logp)
+	key := ingressKeyPrefix + ing.Namespace + "/" + ing.Name
+	o.deletedMux.Lock()
+	o.deleted[key] = deletedIngress{ref: ingressReference(ing), checkName: checkName, checks: checks}
+	o.deletedMux.Unlock()
 
-	hosts := getIngressHosts(ing)
-	if len(hosts) == 0 {
-		return
+	o.queue.Add(key)
+}
+
+// Queues the deleted Ingresses whose checks are left, e.g. because their
+// keys were dropped after maxRetries. They are not in the informer stores,
+// so informer resyncs don't queue them, and garbage collection skips them.
+func (o *Operator) enqueueDeleted() {
+	o.deletedMux.Lock()
+	keys := make([]string, 0, len(o.deleted))
+	for key := range o.deleted {
+		keys = append(keys, key)
 	}
+	o.deletedMux.Unlock()
 
-	checkSpec, ok := o.store.Get(ing.Namespace, checkName)
-	if !ok {
-		checkSpec = defaultCheckSpec
+	for _, key := range keys {
+		o.queue.Add(key)
+	}
+}
+
+// Queues the Ingresses in the namespace referencing the Check spec.
+func (o *Operator) enqueueCheckIngresses(namespace, name string) {
+	for _, ing := range o.listIngresses() {
+		if checkName, ok := o.annotation(ing); ok && ing.Namespace == namespace && checkName == name {
+			o.enqueueIngress(ing)
+		}
+	}
+}
+
+// Processes keys until the queue is shut down. Keys left in the queue once
+// stopc is closed are not processed.
+func (o *Operator) worker(stopc <-chan struct{}) {
+	for {
+		select {
+		case <-stopc:
+			return
+		default:
+		}
+		if !o.processNextItem() {
+			return
+		}
+	}
+}
+
+func (o *Operator) processNextItem() bool {
+	key, quit := o.queue.Get()
+	if quit {
+		return false
+	}
+	defer o.queue.Done(key)
+	defer o.heartbeat.beat()
+
+	err := o.sync(key)
+	if err == nil {
+		o.queue.Forget(key)
+		return true
+	}
+
+	logger := log.WithField("key", key).WithError(err)
+	if o.queue.NumRequeues(key) < maxRetries {
+		logger.Error("Error syncing, retrying")
+		o.queue.AddRateLimited(key)
+		return true
+	}
+
+	logger.Error("Error syncing, giving up")
+	o.queue.Forget(key)
+	return true
+}
+
+func (o *Operator) sync(key string) (err error) {
+	start := time.Now()
+	switch {
+	case strings.HasPrefix(key, ingressKeyPrefix):
+		defer func() { observeSync(kindIngress, start, err) }()
+		return o.syncIngress(key)
+	case strings.HasPrefix(key, checkSpecKeyPrefix):
+		defer func() { observeSync(kindCheck, start, err) }()
+		return o.syncCheckSpec(key)
+	case key == heartbeatKey:
+		return nil
+	default:
+		return fmt.Errorf("unknown key %s", key)
+	}
+}
+
+func (o *Operator) syncIngress(key string) error {
+	o.deletedMux.Lock()
+	deleted, ok := o.deleted[key]
+	o.deletedMux.Unlock()
+
+	// Delete checks of the deleted Ingress first, an Ingress with the same
+	// name may have been created since.
+	if ok {
+		left := o.handleDeleteIngress(deleted)
+
+		o.deletedMux.Lock()
+		if len(left) == 0 {
+			delete(o.deleted, key)
+			if o.planned != nil {
+				o.planned.forget(strings.TrimPrefix(key, ingressKeyPrefix))
+			}
+		} else {
+			o.deleted[key] = deletedIngress{ref: deleted.ref, checkName: deleted.checkName, checks: left}
+		}
+		o.deletedMux.Unlock()
+
+		if len(left) > 0 {
+			return fmt.Errorf("failed to delete %d checks", len(left))
+		}
 	}
 
-	err := o.createChecks(logp, ing, hosts, checkName, checkSpec)
+	ing, exists, err := o.getIngress(strings.TrimPrefix(key, ingressKeyPrefix))
 	if err != nil {
-		log.Errorf("%s error: %v", logp, err)
+		return err
 	}
+	if !exists {
+		return nil
+	}
+
+	err = o.handleIngress(ing)
+	if err != nil && ing.ObjectMeta.DeletionTimestamp != nil && o.queue.NumRequeues(key) >= finalizeEventRetries {
+		o.recordEvent(ing, v1.EventTypeWarning, reasonDeleteChecksFailed, err.Error())
+	}
+	return err
 }
 
-// Delete Pingdom checks if the ingress has the annotation.
-func (o *Operator) handleDeleteIngress(ing *v1beta1.Ingress) {
-	checkName, ok := annotation(ing)
-	if !ok {
-		return
+func (o *Operator) syncCheckSpec(key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(strings.TrimPrefix(key, checkSpecKeyPrefix))
+	if err != nil {
+		return err
 	}
 
-	logp := fmt.Sprintf("DeleteIngress[%d]", atomic.AddUint64(&o.eventCnt, 1))
-	log.Debugf("%s obj=%s", logp, ing.Name)
-	defer log.Debugf("%s end", logp)
+	// Checks of namespaces which are no longer watched are left as they
+	// are.
+	ni := o.informersFor(namespace)
+	if ni == nil {
+		return nil
+	}
 
-	err := o.deleteChecks(logp, ing, checkName)
+	check, err := ni.checkLister.Checks(namespace).Get(name)
+	if errors.IsNotFound(err) {
+		return o.handleDeleteCheckSpec(namespace, name)
+	}
 	if err != nil {
-		log.Errorf("%s error: %v", logp, err)
+		return err
 	}
+	return o.handleSetCheckSpec(namespace, name, check.Spec)
 }
 
-// Update Pingdom checks if the ingress has the annotation.
-func (o *Operator) handleUpdateIngress(old, new *v1beta1.Ingress) {
-	// TODO at least remove checks if new is not annotated and pass checks otherwise
-	if _, ok := annotation(new); !ok {
-		return
+// Rebuilds the checks registry from the checks annotation of existing
+// Ingresses. Checks which no longer exist in Pingdom are skipped, unless
+// Pingdom can't be reached.
+func (o *Operator) rebuildChecks() {
+	logger := o.eventLogger("rebuildChecks")
+	logger.Debug("Start")
+	defer logger.Debug("End")
+
+	existing, err := o.listChecks()
+	if err != nil {
+		logger.WithError(err).Warning("Error listing checks, using annotations only")
+	}
+
+	var cnt int
+	for _, ing := range o.listIngresses() {
+		checkName, ok := o.annotation(ing)
+		if !ok {
+			continue
+		}
+
+		checks, err := o.getChecks(ing)
+		if err != nil {
+			logger.WithFields(ingressFields(ing)).WithError(err).Error("Error getting checks of ingress")
+			continue
+		}
+
+		for host, id := range checks {
+			if existing != nil {
+				if _, ok := existing[id]; !ok {
+					logger.WithFields(logrus.Fields{"host": host, "check_id": id}).Debug("Check not found in Pingdom")
+					continue
+				}
+			}
+			o.checks.Add(ing.Namespace, checkName, id)
+			cnt++
+		}
+	}
+
+	logger.WithField("checks", cnt).Info("Rebuilt checks registry")
+}
+
+// Reconcile Pingdom checks if the ingress has or had the annotation. This
+// is also called on every informer resync.
+func (o *Operator) handleIngress(ing *v1beta1.Ingress) error {
+	if _, ok := o.annotation(ing); !ok && !o.hasChecks(ing) && !hasFinalizer(ing) {
+		return nil
 	}
 
-	logp := fmt.Sprintf("UpdateIngress[%d]", atomic.AddUint64(&o.eventCnt, 1))
-	log.Debugf("%s NOT YET IMPLEMENTED old=%s new=%s", logp, old.Name, new.Name)
-	defer log.Debugf("%s end", logp)
+	logger := o.eventLogger("syncIngress").WithFields(ingressFields(ing))
+	logger.Debug("Start")
+	defer logger.Debug("End")
+
+	return o.reconcile(logger, ing)
+}
+
+// Delete Pingdom checks of the deleted ingress. Returns the checks which
+// failed to be deleted.
+func (o *Operator) handleDeleteIngress(ing deletedIngress) map[string]int {
+	logger := o.eventLogger("deleteIngress").WithFields(logrus.Fields{"namespace": ing.ref.Namespace, "ingress": ing.ref.Name})
+	logger.Debug("Start")
+	defer logger.Debug("End")
+
+	return o.deleteChecks(logger, ing.ref, ing.checks, ing.checkName)
 }
 
-func (o *Operator) handleSetCheckSpec(namespace, name string, checkSpec tpr.Spec) {
-	logp := fmt.Sprintf("SetCheckSpec[%d]", atomic.AddUint64(&o.eventCnt, 1))
-	log.Debugf("%s namespace=%s name=%s", logp, namespace, name)
-	defer log.Debugf("%s end", logp)
+func (o *Operator) handleSetCheckSpec(namespace, name string, checkSpec tpr.Spec) error {
+	logger := o.eventLogger("setCheckSpec").WithFields(logrus.Fields{"namespace": namespace, "check": name})
+	logger.Debug("Start")
+	defer logger.Debug("End")
 
-	for _, id := range o.checks.Get(name) {
-		err := o.updateCheck(id, checkSpec)
+	var errs []error
+	var replace bool
+	for _, id := range o.checks.Get(namespace, name) {
+		err := o.updateCheck(namespace, id, checkSpec)
 		if err == nil {
-			log.Debugf("%s updated checkID=%d", logp, id)
+			logger.WithField("check_id", id).Debug("Updated check")
+			o.recordCheckEvent(nil, namespace, name, v1.EventTypeNormal, reasonCheckUpdated, "Updated Pingdom check %d", id)
+		} else if err == errCheckTypeChanged {
+			replace = true
 		} else {
-			log.Debugf("%s error updating checkID=%d:", logp, id, err)
+			errs = append(errs, fmt.Errorf("updating checkID=%d: %v", id, err))
+			o.recordCheckEvent(nil, namespace, name, v1.EventTypeWarning, reasonUpdateCheckFailed, "Error updating Pingdom check %d: %v", id, err)
+		}
+	}
+
+	// Checks are replaced when reconciling their Ingresses, which also
+	// updates the checks annotation.
+	if replace {
+		logger.Debug("Check type changed, queueing ingresses")
+		o.enqueueCheckIngresses(namespace, name)
+	}
+
+	if !replace && len(errs) == 0 {
+		if err := o.setCheckSpecAnnotations(namespace, name, checkSpec); err != nil {
+			logger.WithError(err).Error("Error recording spec of ingresses")
 		}
 	}
+
+	err := utilerrors.NewAggregate(errs)
+	if serr := o.updateCheckStatus(namespace, name, checkSpec, err); serr != nil {
+		logger.WithError(serr).Error("Error updating status")
+	}
+	return err
 }
 
-func (o *Operator) handleDeleteCheckSpec(namespace, name string, checkSpec tpr.Spec) {
-	logp := fmt.Sprintf("DeleteCheckSpec[%d]", atomic.AddUint64(&o.eventCnt, 1))
-	log.Debugf("%s namespace=%s name=%s", logp, namespace, name)
-	defer log.Debugf("%s end", logp)
+func (o *Operator) handleDeleteCheckSpec(namespace, name string) error {
+	logger := o.eventLogger("deleteCheckSpec").WithFields(logrus.Fields{"namespace": namespace, "check": name})
+	logger.Debug("Start")
+	defer logger.Debug("End")
 
-	for _, id := range o.checks.Get(name) {
-		err := o.updateCheck(id, defaultCheckSpec)
+	var errs []error
+	var replace bool
+	for _, id := range o.checks.Get(namespace, name) {
+		err := o.updateCheck(namespace, id, o.config.DefaultCheckSpec)
 		if err == nil {
-			log.Debugf("%s setting default checkID=%d", logp, id)
+			logger.WithField("check_id", id).Debug("Set default spec of check")
+		} else if err == errCheckTypeChanged {
+			replace = true
 		} else {
-			log.Debugf("%s error setting default checkID=%d: %v", logp, id, err)
+			errs = append(errs, fmt.Errorf("setting default checkID=%d: %v", id, err))
 		}
 	}
+
+	if replace {
+		logger.Debug("Check type changed, queueing ingresses")
+		o.enqueueCheckIngresses(namespace, name)
+	} else if len(errs) == 0 {
+		if err := o.setCheckSpecAnnotations(namespace, name, o.config.DefaultCheckSpec); err != nil {
+			logger.WithError(err).Error("Error recording spec of ingresses")
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// Records the hash of the spec on the Ingresses with checks using the
+// Check, once all the checks of the Check were updated with the spec.
+func (o *Operator) setCheckSpecAnnotations(namespace, name string, checkSpec tpr.Spec) error {
+	hash, err := o.specHash(namespace, checkSpec)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, ing := range o.listIngresses() {
+		if checkName, ok := o.annotation(ing); ok && ing.Namespace == namespace && checkName == name && o.hasChecks(ing) {
+			if err := o.setSpecAnnotation(ing, hash); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return utilerrors.NewAggregate(errs)
 }
 
 // Create a check for each host in the Ingress and annotates it
-// with the checks metadata.
-func (o *Operator) createChecks(logp string, ing *v1beta1.Ingress, hosts []string, checkName string, checkSpec tpr.Spec) error {
+// with the checks metadata. Checks already listed in the annotation
+// are kept.
+func (o *Operator) createChecks(logger *logrus.Entry, ing *v1beta1.Ingress, hosts []string, checkName string, checkSpec tpr.Spec) error {
 	phosts := make(map[string]int)
+	owner := o.owner(ing)
+	ref := ingressReference(ing)
 
+	var failed int
 	for _, h := range hosts {
-		id, err := o.createCheck(h, checkSpec)
+		id, err := o.createCheck(ing.Namespace, owner.checkName(h), h, checkSpec)
 		if err == nil {
 			phosts[h] = id
-			o.checks.Add(checkName, id)
-			log.Debugf("%s added Pingdom check %d for host %s", logp, id, h)
+			o.checks.Add(ing.Namespace, checkName, id)
+			logger.WithFields(logrus.Fields{"host": h, "check_id": id}).Debug("Added Pingdom check")
+			o.recordCheckEvent(ref, ing.Namespace, checkName, v1.EventTypeNormal, reasonCheckCreated, "Created Pingdom check %d for host %s", id, h)
 		} else {
-			log.Errorf("%s error: adding Pingdom check for host %s: %v", logp, h, err)
+			failed++
+			logger.WithField("host", h).WithError(err).Error("Error adding Pingdom check")
+			o.recordCheckEvent(ref, ing.Namespace, checkName, v1.EventTypeWarning, reasonCreateCheckFailed, "Error creating Pingdom check for host %s: %v", h, err)
 		}
 	}
 
-	bytes, _ := json.Marshal(phosts)
-	data := string(bytes)
+	// Get a fresh copy of the ingress before updating.
+	ing, err := o.kclient.ExtensionsV1beta1().Ingresses(ing.Namespace).Get(ing.Name)
+	if err != nil {
+		return fmt.Errorf("getting ingress: %v", err)
+	}
+
+	checks, err := o.getChecks(ing)
+	if err != nil {
+		return err
+	}
+	for h, id := range phosts {
+		checks[h] = id
+	}
+
+	err = o.setChecksAnnotation(ing, checks)
+	if err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to add %d checks", failed)
+	}
+	return nil
+}
+
+// Delete the given checks of the Ingress. Returns the checks which failed
+// to be deleted.
+func (o *Operator) deleteChecks(logger *logrus.Entry, ing *v1.ObjectReference, checks map[string]int, checkName string) map[string]int {
+	left := make(map[string]int)
+
+	for host, id := range checks {
+		err := o.deleteCheck(id)
+		if err == nil {
+			o.checks.Delete(ing.Namespace, checkName, id)
+			logger.WithFields(logrus.Fields{"host": host, "check_id": id}).Debug("Deleted Pingdom check")
+			o.recordCheckEvent(ing, ing.Namespace, checkName, v1.EventTypeNormal, reasonCheckDeleted, "Deleted Pingdom check %d for host %s", id, host)
+		} else {
+			left[host] = id
+			logger.WithFields(logrus.Fields{"host": host, "check_id": id}).WithError(err).Error("Error deleting Pingdom check")
+			o.recordCheckEvent(ing, ing.Namespace, checkName, v1.EventTypeWarning, reasonDeleteCheckFailed, "Error deleting Pingdom check %d for host %s: %v", id, host, err)
+		}
+	}
+
+	return left
+}
+
+// Sets the checks annotation with the hosts and check IDs. The annotation
+// is removed if there are no checks. The finalizer is kept while the
+// Ingress has checks or is annotated and not being deleted.
+func (o *Operator) setChecksAnnotation(ing *v1beta1.Ingress, checks map[string]int) error {
+	if o.planned != nil {
+		return o.setPlannedChecks(ing, checks)
+	}
 
 	// Get a fresh copy of the ingress before updating.
-	ing, err := o.kclient.Ingresses(ing.Namespace).Get(ing.Name)
+	ing, err := o.kclient.ExtensionsV1beta1().Ingresses(ing.Namespace).Get(ing.Name)
 	if err != nil {
 		return fmt.Errorf("getting ingress: %v", err)
 	}
 
-	// Add annotation with the hosts and check IDs.
-	ing.ObjectMeta.Annotations[checksAnnotation] = data
+	var changed bool
+	if len(checks) == 0 {
+		if o.hasChecks(ing) {
+			delete(ing.ObjectMeta.Annotations, o.config.ChecksAnnotation)
+			changed = true
+		}
+		if _, ok := ing.ObjectMeta.Annotations[o.specAnnotation()]; ok {
+			delete(ing.ObjectMeta.Annotations, o.specAnnotation())
+			changed = true
+		}
+	} else {
+		bytes, _ := json.Marshal(checks)
+		data := string(bytes)
+		if ing.ObjectMeta.Annotations == nil {
+			ing.ObjectMeta.Annotations = make(map[string]string)
+		}
+		if ing.ObjectMeta.Annotations[o.config.ChecksAnnotation] != data {
+			ing.ObjectMeta.Annotations[o.config.ChecksAnnotation] = data
+			changed = true
+		}
+	}
+
+	_, annotated := o.annotation(ing)
+	keep := len(checks) > 0 || (annotated && ing.ObjectMeta.DeletionTimestamp == nil)
+	if setFinalizer(ing, keep) {
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
 
-	_, err = o.kclient.Ingresses(ing.Namespace).Update(ing)
+	_, err = o.kclient.ExtensionsV1beta1().Ingresses(ing.Namespace).Update(ing)
 	if err != nil {
 		return fmt.Errorf("updating ingress: %v", err)
 	}
@@ -251,78 +770,150 @@ func (o *Operator) createChecks(logp string, ing *v1beta1.Ingress, hosts []strin
 	return nil
 }
 
-// Delete all checks before the Ingress is deleted.
-func (o *Operator) deleteChecks(logp string, ing *v1beta1.Ingress, checkName string) error {
-	data, ok := ing.ObjectMeta.Annotations[checksAnnotation]
-	if !ok {
+// Annotation of Ingresses with the hash of the spec their checks were last
+// updated with.
+func (o *Operator) specAnnotation() string {
+	return o.config.ChecksAnnotation + "_spec"
+}
+
+// Records the hash of the spec the checks of the Ingress were updated
+// with. Ingresses are left untouched in dry-run mode.
+func (o *Operator) setSpecAnnotation(ing *v1beta1.Ingress, hash string) error {
+	if o.planned != nil {
 		return nil
 	}
 
-	var hosts map[string]int
-	err := json.Unmarshal([]byte(data), &hosts)
+	// Get a fresh copy of the ingress before updating.
+	ing, err := o.kclient.ExtensionsV1beta1().Ingresses(ing.Namespace).Get(ing.Name)
 	if err != nil {
-		return fmt.Errorf("unmarshaling checks json: %v", err)
+		return fmt.Errorf("getting ingress: %v", err)
 	}
-
-	for host, id := range hosts {
-		err := o.deleteCheck(id)
-		if err == nil {
-			o.checks.Delete(checkName, id)
-			log.Debugf("%s deleted check %d for host %s", logp, id, host)
-		} else {
-			log.Errorf("%s error deleting check %d for host %s", logp, id, host)
-		}
+	if ing.ObjectMeta.Annotations[o.specAnnotation()] == hash {
+		return nil
 	}
+	if ing.ObjectMeta.Annotations == nil {
+		ing.ObjectMeta.Annotations = make(map[string]string)
+	}
+	ing.ObjectMeta.Annotations[o.specAnnotation()] = hash
 
+	_, err = o.kclient.ExtensionsV1beta1().Ingresses(ing.Namespace).Update(ing)
+	if err != nil {
+		return fmt.Errorf("updating ingress: %v", err)
+	}
 	return nil
 }
 
-func annotation(ing *v1beta1.Ingress) (v string, ok bool) {
-	v, ok = ing.ObjectMeta.Annotations[pingdomAnnotation]
+// Returns the logger of an operation with a unique event ID, to tell the
+// logs of concurrent operations apart.
+func (o *Operator) eventLogger(operation string) *logrus.Entry {
+	return log.WithFields(logrus.Fields{
+		"event_id":  atomic.AddUint64(&o.eventCnt, 1),
+		"operation": operation,
+	})
+}
+
+func ingressFields(ing *v1beta1.Ingress) logrus.Fields {
+	return logrus.Fields{"namespace": ing.Namespace, "ingress": ing.Name}
+}
+
+// Returns the check spec with the given name or the default spec if
+// there is no such Check resource.
+func (o *Operator) checkSpec(namespace, checkName string) tpr.Spec {
+	ni := o.informersFor(namespace)
+	if ni == nil {
+		return o.config.DefaultCheckSpec
+	}
+	check, err := ni.checkLister.Checks(namespace).Get(checkName)
+	if err != nil {
+		return o.config.DefaultCheckSpec
+	}
+	return check.Spec
+}
+
+// Returns the name of the Check resource set by the annotation.
+func (o *Operator) annotation(ing *v1beta1.Ingress) (v string, ok bool) {
+	v, ok = ing.ObjectMeta.Annotations[o.config.Annotation]
 	return
 }
 
-func hasChecks(ing *v1beta1.Ingress) bool {
-	v, _ := ing.ObjectMeta.Annotations[checksAnnotation]
-	return len(v) > 0
+func hasFinalizer(ing *v1beta1.Ingress) bool {
+	for _, f := range ing.ObjectMeta.Finalizers {
+		if f == checksFinalizer {
+			return true
+		}
+	}
+	return false
 }
 
-// Returns Ingress hosts
-func getIngressHosts(ing *v1beta1.Ingress) []string {
-	hosts := make([]string, 0)
-	for _, r := range ing.Spec.Rules {
-		if r.Host != "" {
-			hosts = append(hosts, r.Host)
+// Adds or removes the finalizer. Returns true if the Ingress was changed.
+func setFinalizer(ing *v1beta1.Ingress, keep bool) bool {
+	if keep == hasFinalizer(ing) {
+		return false
+	}
+	if keep {
+		ing.ObjectMeta.Finalizers = append(ing.ObjectMeta.Finalizers, checksFinalizer)
+		return true
+	}
+
+	var finalizers []string
+	for _, f := range ing.ObjectMeta.Finalizers {
+		if f != checksFinalizer {
+			finalizers = append(finalizers, f)
 		}
 	}
-	return hosts
+	ing.ObjectMeta.Finalizers = finalizers
+	return true
 }
 
-type addIngressEvent struct {
-	obj interface{}
+func (o *Operator) hasChecks(ing *v1beta1.Ingress) bool {
+	if o.planned != nil {
+		if checks, ok := o.planned.get(ing); ok {
+			return len(checks) > 0
+		}
+	}
+	v, _ := ing.ObjectMeta.Annotations[o.config.ChecksAnnotation]
+	return len(v) > 0
 }
 
-func (e addIngressEvent) Ingress() *v1beta1.Ingress { return e.obj.(*v1beta1.Ingress) }
+// Returns the hosts and check IDs from the checks annotation, or the
+// planned checks in dry-run mode once the Ingress was synced.
+func (o *Operator) getChecks(ing *v1beta1.Ingress) (map[string]int, error) {
+	if o.planned != nil {
+		if checks, ok := o.planned.get(ing); ok {
+			return checks, nil
+		}
+	}
 
-type deleteIngressEvent struct {
-	obj interface{}
-}
+	checks := make(map[string]int)
 
-func (e deleteIngressEvent) Ingress() *v1beta1.Ingress { return e.obj.(*v1beta1.Ingress) }
+	data, ok := ing.ObjectMeta.Annotations[o.config.ChecksAnnotation]
+	if !ok || len(data) == 0 {
+		return checks, nil
+	}
 
-type updateIngressEvent struct {
-	old, new interface{}
+	err := json.Unmarshal([]byte(data), &checks)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshaling checks json: %v", err)
+	}
+	return checks, nil
 }
 
-func (e updateIngressEvent) OldIngress() *v1beta1.Ingress { return e.old.(*v1beta1.Ingress) }
-func (e updateIngressEvent) NewIngress() *v1beta1.Ingress { return e.new.(*v1beta1.Ingress) }
-
-type setCheckSpecEvent struct {
-	Namespace, Name string
-	Check           tpr.Spec
+func containsHost(hosts []string, host string) bool {
+	for _, h := range hosts {
+		if h == host {
+			return true
+		}
+	}
+	return false
 }
 
-type deleteCheckSpecEvent struct {
-	Namespace, Name string
-	Check           tpr.Spec
+// Returns Ingress hosts
+func getIngressHosts(ing *v1beta1.Ingress) []string {
+	hosts := make([]string, 0)
+	for _, r := range ing.Spec.Rules {
+		if r.Host != "" {
+			hosts = append(hosts, r.Host)
+		}
+	}
+	return hosts
 }
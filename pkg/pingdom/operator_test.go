@@ -30,3 +30,74 @@ func TestGetIngressHostsWithAnnotation(t *testing.T) {
 	assert.Equal(t, "test.example.com", hosts[0])
 	assert.Equal(t, "test.example.org", hosts[1])
 }
+
+func TestGetChecks(t *testing.T) {
+	ing := v1beta1.Ingress{
+		ObjectMeta: v1.ObjectMeta{
+			Annotations: map[string]string{
+				"monitoring.rossfairbanks.com/pingdom":        "test",
+				"monitoring.rossfairbanks.com/pingdom_checks": `{"test.example.com":1,"test.example.org":2}`,
+			},
+		},
+	}
+
+	o := &Operator{config: DefaultConfig()}
+	checks, err := o.getChecks(&ing)
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"test.example.com": 1, "test.example.org": 2}, checks)
+}
+
+func TestGetChecksWithoutAnnotation(t *testing.T) {
+	ing := v1beta1.Ingress{}
+
+	o := &Operator{config: DefaultConfig()}
+	checks, err := o.getChecks(&ing)
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{}, checks)
+}
+
+func TestSetFinalizer(t *testing.T) {
+	ing := v1beta1.Ingress{
+		ObjectMeta: v1.ObjectMeta{
+			Finalizers: []string{"other"},
+		},
+	}
+
+	assert.True(t, setFinalizer(&ing, true))
+	assert.True(t, hasFinalizer(&ing))
+	assert.False(t, setFinalizer(&ing, true))
+	assert.Equal(t, []string{"other", checksFinalizer}, ing.Finalizers)
+
+	assert.True(t, setFinalizer(&ing, false))
+	assert.False(t, hasFinalizer(&ing))
+	assert.False(t, setFinalizer(&ing, false))
+	assert.Equal(t, []string{"other"}, ing.Finalizers)
+}
+
+func TestCustomAnnotations(t *testing.T) {
+	ing := v1beta1.Ingress{
+		ObjectMeta: v1.ObjectMeta{
+			Annotations: map[string]string{
+				"monitoring.rossfairbanks.com/pingdom":        "default",
+				"monitoring.rossfairbanks.com/pingdom_checks": `{"test.example.com":1}`,
+				"example.com/pingdom":                         "custom",
+				"example.com/pingdom-checks":                  `{"test.example.com":2}`,
+			},
+		},
+	}
+
+	config := DefaultConfig()
+	config.Annotation = "example.com/pingdom"
+	config.ChecksAnnotation = "example.com/pingdom-checks"
+	o := &Operator{config: config}
+
+	checkName, ok := o.annotation(&ing)
+	assert.True(t, ok)
+	assert.Equal(t, "custom", checkName)
+
+	checks, err := o.getChecks(&ing)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"test.example.com": 2}, checks)
+}
@@ -1,46 +1,185 @@
 package pingdom
 
 import (
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"strconv"
+	"time"
 
 	"github.com/rossf7/pingdom-operator/pkg/tpr"
 	pdom "github.com/russellcardullo/go-pingdom/pingdom"
 )
 
+// ChecksAPI is the part of the Pingdom checks API used by the operator. It
+// is implemented by the CheckService of the go-pingdom client.
+type ChecksAPI interface {
+	List(params ...map[string]string) ([]pdom.CheckResponse, error)
+	Create(check pdom.Check) (*pdom.CheckResponse, error)
+	Read(id int) (*pdom.CheckResponse, error)
+	Update(id int, check pdom.Check) (*pdom.PingdomResponse, error)
+	Delete(id int) (*pdom.PingdomResponse, error)
+}
+
 var (
 	defaultCheckSpec = tpr.Spec{
 		Resolution: 1,
 	}
 )
 
-// Creates a HTTP check for the host and returns the Pingdom ID.
-func (c *Operator) createCheck(host string, checkSpec tpr.Spec) (int, error) {
-	hc := pdom.HttpCheck{Name: host, Hostname: host, Resolution: checkSpec.Resolution}
-	check, err := c.pclient.Checks.Create(&hc)
+// Creates a check for the host and returns the Pingdom ID.
+func (c *Operator) createCheck(namespace, name, host string, checkSpec tpr.Spec) (int, error) {
+	ck, err := c.buildCheck(namespace, name, host, checkSpec)
+	if err != nil {
+		return -1, err
+	}
+	start := time.Now()
+	check, err := c.pclient.Create(ck)
+	c.observeAPI("createCheck", start, err)
 	if err != nil {
 		return -1, err
 	}
 	return check.ID, nil
 }
 
-// Updates a HTTP check.
-func (c *Operator) updateCheck(id int, checkSpec tpr.Spec) error {
-	r, err := c.pclient.Checks.Read(id)
+// Updates a check. Returns errCheckTypeChanged if the check has a different
+// type than the spec.
+func (c *Operator) updateCheck(namespace string, id int, checkSpec tpr.Spec) error {
+	start := time.Now()
+	r, err := c.pclient.Read(id)
+	c.observeAPI("readCheck", start, err)
 	if err != nil {
 		return fmt.Errorf("reading check with id:%d: %v", id, err)
 	}
-	hc := pdom.HttpCheck{
-		Name:                     r.Name,
-		Hostname:                 r.Hostname,
-		Resolution:               checkSpec.Resolution,
-		SendNotificationWhenDown: r.SendNotificationWhenDown,
+	if typeChanged(*r, checkSpec) {
+		return errCheckTypeChanged
 	}
-	_, err = c.pclient.Checks.Update(id, &hc)
+	if checkSpec.NotifyAfterFailures == 0 {
+		checkSpec.NotifyAfterFailures = r.SendNotificationWhenDown
+	}
+	ck, err := c.buildCheck(namespace, r.Name, r.Hostname, checkSpec)
+	if err != nil {
+		return err
+	}
+	start = time.Now()
+	_, err = c.pclient.Update(id, ck)
+	c.observeAPI("updateCheck", start, err)
 	return err
 }
 
-// Deletes the HTTP check.
+// Builds a HTTP check for the host from the spec.
+func (c *Operator) httpCheck(namespace, name, host string, checkSpec tpr.Spec) (*pdom.HttpCheck, error) {
+	hc := &pdom.HttpCheck{
+		Name:                     name,
+		Hostname:                 host,
+		Resolution:               checkSpec.Resolution,
+		Url:                      checkSpec.URL,
+		Encryption:               checkSpec.Encryption,
+		Port:                     checkSpec.Port,
+		ShouldContain:            checkSpec.ShouldContain,
+		ShouldNotContain:         checkSpec.ShouldNotContain,
+		RequestHeaders:           checkSpec.RequestHeaders,
+		Paused:                   checkSpec.Paused,
+		SendNotificationWhenDown: checkSpec.NotifyAfterFailures,
+		NotifyAgainEvery:         checkSpec.NotifyAgainEvery,
+		ContactIds:               checkSpec.ContactIDs,
+		IntegrationIds:           checkSpec.IntegrationIDs,
+	}
+
+	if checkSpec.BasicAuth != nil {
+		username, password, err := c.basicAuth(namespace, checkSpec.BasicAuth)
+		if err != nil {
+			return nil, err
+		}
+		hc.Username = username
+		hc.Password = password
+	}
+
+	return hc, nil
+}
+
+// Reads basic auth credentials from the Secret.
+func (c *Operator) basicAuth(namespace string, auth *tpr.BasicAuth) (username, password string, err error) {
+	secret, err := c.kclient.CoreV1().Secrets(namespace).Get(auth.SecretName)
+	if err != nil {
+		return "", "", fmt.Errorf("getting basic auth secret %s/%s: %v", namespace, auth.SecretName, err)
+	}
+
+	usernameKey, passwordKey := auth.UsernameKey, auth.PasswordKey
+	if usernameKey == "" {
+		usernameKey = "username"
+	}
+	if passwordKey == "" {
+		passwordKey = "password"
+	}
+
+	u, ok := secret.Data[usernameKey]
+	if !ok {
+		return "", "", fmt.Errorf("basic auth secret %s/%s has no key %s", namespace, auth.SecretName, usernameKey)
+	}
+	p, ok := secret.Data[passwordKey]
+	if !ok {
+		return "", "", fmt.Errorf("basic auth secret %s/%s has no key %s", namespace, auth.SecretName, passwordKey)
+	}
+	return string(u), string(p), nil
+}
+
+// Lists all checks in Pingdom by ID.
+func (c *Operator) listChecks() (map[int]pdom.CheckResponse, error) {
+	start := time.Now()
+	list, err := c.pclient.List()
+	c.observeAPI("listChecks", start, err)
+	if err != nil {
+		return nil, err
+	}
+	checks := make(map[int]pdom.CheckResponse, len(list))
+	for _, r := range list {
+		checks[r.ID] = r
+	}
+	return checks, nil
+}
+
+// Returns a hash of the spec, and of the version of its basic auth Secret.
+// Checks built from a spec with another hash need updating, including for
+// fields which can't be compared with the listed checks.
+func (c *Operator) specHash(namespace string, checkSpec tpr.Spec) (string, error) {
+	data, err := json.Marshal(checkSpec)
+	if err != nil {
+		return "", err
+	}
+	h := fnv.New64a()
+	h.Write(data)
+
+	// The password itself is not hashed, the hash is stored in an
+	// annotation.
+	if checkSpec.BasicAuth != nil {
+		secret, err := c.kclient.CoreV1().Secrets(namespace).Get(checkSpec.BasicAuth.SecretName)
+		if err != nil {
+			return "", fmt.Errorf("getting basic auth secret %s/%s: %v", namespace, checkSpec.BasicAuth.SecretName, err)
+		}
+		h.Write([]byte(secret.ResourceVersion))
+	}
+	return strconv.FormatUint(h.Sum64(), 16), nil
+}
+
+// Returns true if the check is for the host and matches the spec. Only
+// the fields returned when listing checks are compared, other fields are
+// covered by the spec hash.
+func checkMatches(r pdom.CheckResponse, host string, checkSpec tpr.Spec) bool {
+	return r.Hostname == host &&
+		r.Resolution == checkSpec.Resolution &&
+		(r.Status == "paused") == checkSpec.Paused
+}
+
+// Returns true if the check has a different type than the spec.
+func typeChanged(r pdom.CheckResponse, checkSpec tpr.Spec) bool {
+	return r.Type.Name != "" && r.Type.Name != checkSpec.CheckType()
+}
+
+// Deletes the check.
 func (c *Operator) deleteCheck(checkID int) error {
-	_, err := c.pclient.Checks.Delete(checkID)
+	start := time.Now()
+	_, err := c.pclient.Delete(checkID)
+	c.observeAPI("deleteCheck", start, err)
 	return err
 }
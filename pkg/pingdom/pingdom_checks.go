@@ -2,42 +2,49 @@ package pingdom
 
 import "sync"
 
+// pingdomChecks is the registry of the Pingdom check IDs using a Check
+// spec. Check names are only unique within a namespace.
 type pingdomChecks struct {
 	dataMux *sync.Mutex
-	data    map[string][]int
+	data    map[checkKey][]int
+}
+
+// checkKey is the namespace and name of a Check.
+type checkKey struct {
+	namespace string
+	name      string
 }
 
 func newPingdomChecks() *pingdomChecks {
 	return &pingdomChecks{
 		dataMux: new(sync.Mutex),
-		data:    make(map[string][]int),
+		data:    make(map[checkKey][]int),
 	}
 }
 
-func (p *pingdomChecks) Get(checkName string) (ids []int) {
+// Get returns a copy of the ids, Delete and Forget change the stored slice
+// in place.
+func (p *pingdomChecks) Get(namespace, checkName string) (ids []int) {
 	p.dataMux.Lock()
 	defer p.dataMux.Unlock()
 
-	return p.data[checkName]
+	return append([]int(nil), p.data[checkKey{namespace, checkName}]...)
 }
 
-func (p *pingdomChecks) Add(checkName string, ids ...int) {
+func (p *pingdomChecks) Add(namespace, checkName string, ids ...int) {
 	p.dataMux.Lock()
 	defer p.dataMux.Unlock()
 
-	s := p.data[checkName]
-	if s == nil {
-		p.data[checkName] = ids
-		return
-	}
-	p.data[checkName] = append(s, ids...)
+	key := checkKey{namespace, checkName}
+	p.data[key] = append(p.data[key], ids...)
 }
 
-func (p *pingdomChecks) Delete(checkName string, ids ...int) {
+func (p *pingdomChecks) Delete(namespace, checkName string, ids ...int) {
 	p.dataMux.Lock()
 	defer p.dataMux.Unlock()
 
-	s := p.data[checkName]
+	key := checkKey{namespace, checkName}
+	s := p.data[key]
 	if s == nil {
 		return
 	}
@@ -50,5 +57,36 @@ func (p *pingdomChecks) Delete(checkName string, ids ...int) {
 		}
 	}
 
-	p.data[checkName] = s
+	p.data[key] = s
+}
+
+// Forget removes the ids from all Checks.
+func (p *pingdomChecks) Forget(ids ...int) {
+	p.dataMux.Lock()
+	defer p.dataMux.Unlock()
+
+	for key, s := range p.data {
+		for _, toDelete := range ids {
+			for i := len(s) - 1; i >= 0; i-- {
+				if s[i] == toDelete {
+					s = append(s[:i], s[i+1:]...)
+				}
+			}
+		}
+		p.data[key] = s
+	}
+}
+
+// Counts returns the number of checks by Check.
+func (p *pingdomChecks) Counts() map[checkKey]int {
+	p.dataMux.Lock()
+	defer p.dataMux.Unlock()
+
+	counts := make(map[checkKey]int, len(p.data))
+	for key, s := range p.data {
+		if len(s) > 0 {
+			counts[key] = len(s)
+		}
+	}
+	return counts
 }
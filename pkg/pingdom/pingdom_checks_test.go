@@ -1,6 +1,7 @@
 package pingdom
 
 import (
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -8,15 +9,71 @@ import (
 
 func TestPingdomChecks(t *testing.T) {
 	p := newPingdomChecks()
-	assert.Equal(t, []int(nil), p.Get("a"))
-	p.Add("a", 1, 3, 5, 7)
-	assert.Equal(t, []int{1, 3, 5, 7}, p.Get("a"))
-	p.Delete("a", 3, 7)
-	assert.Equal(t, []int{1, 5}, p.Get("a"))
-	p.Add("a", 7, 8)
-	assert.Equal(t, []int{1, 5, 7, 8}, p.Get("a"))
-	p.Add("a", 9)
-	assert.Equal(t, []int{1, 5, 7, 8, 9}, p.Get("a"))
-	p.Delete("a", 7)
-	assert.Equal(t, []int{1, 5, 8, 9}, p.Get("a"))
+	assert.Equal(t, []int(nil), p.Get("ns", "a"))
+	p.Add("ns", "a", 1, 3, 5, 7)
+	assert.Equal(t, []int{1, 3, 5, 7}, p.Get("ns", "a"))
+	p.Delete("ns", "a", 3, 7)
+	assert.Equal(t, []int{1, 5}, p.Get("ns", "a"))
+	p.Add("ns", "a", 7, 8)
+	assert.Equal(t, []int{1, 5, 7, 8}, p.Get("ns", "a"))
+	p.Add("ns", "a", 9)
+	assert.Equal(t, []int{1, 5, 7, 8, 9}, p.Get("ns", "a"))
+	p.Delete("ns", "a", 7)
+	assert.Equal(t, []int{1, 5, 8, 9}, p.Get("ns", "a"))
+}
+
+func TestPingdomChecksForget(t *testing.T) {
+	p := newPingdomChecks()
+	p.Add("ns", "a", 1, 3, 5)
+	p.Add("ns", "b", 2, 3, 4)
+	p.Forget(3, 4)
+	assert.Equal(t, []int{1, 5}, p.Get("ns", "a"))
+	assert.Equal(t, []int{2}, p.Get("ns", "b"))
+}
+
+func TestPingdomChecksCounts(t *testing.T) {
+	p := newPingdomChecks()
+	p.Add("ns", "a", 1, 3, 5)
+	p.Add("ns", "b", 2)
+	p.Delete("ns", "b", 2)
+	assert.Equal(t, map[checkKey]int{{"ns", "a"}: 3}, p.Counts())
+}
+
+func TestPingdomChecksNamespaces(t *testing.T) {
+	p := newPingdomChecks()
+	p.Add("team-a", "web", 1)
+	p.Add("team-b", "web", 2)
+	assert.Equal(t, []int{1}, p.Get("team-a", "web"))
+	assert.Equal(t, []int{2}, p.Get("team-b", "web"))
+	p.Delete("team-a", "web", 2)
+	assert.Equal(t, []int{2}, p.Get("team-b", "web"))
+	assert.Equal(t, map[checkKey]int{{"team-a", "web"}: 1, {"team-b", "web"}: 1}, p.Counts())
+}
+
+// Run with -race.
+func TestPingdomChecksConcurrent(t *testing.T) {
+	p := newPingdomChecks()
+	p.Add("ns", "a", 1, 2, 3, 4)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			p.Delete("ns", "a", 2)
+			p.Add("ns", "a", 2)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			// The ids must not be shifted or duplicated while iterating.
+			seen := make(map[int]bool)
+			for _, id := range p.Get("ns", "a") {
+				assert.False(t, seen[id], "duplicate id %d", id)
+				seen[id] = true
+			}
+		}
+	}()
+	wg.Wait()
 }
@@ -0,0 +1,272 @@
+package pingdom
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rossf7/pingdom-operator/pkg/util"
+	pdom "github.com/russellcardullo/go-pingdom/pingdom"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/client-go/pkg/util/flowcontrol"
+)
+
+// Default backoff of retried Pingdom API requests.
+var defaultAPIBackoff = util.Backoff{
+	Base:   time.Second,
+	Max:    30 * time.Second,
+	Jitter: 0.5,
+}
+
+// Headers of Pingdom responses with the remaining requests of the short
+// and long rate-limit windows, e.g. "Remaining: 394 Time until reset: 3589".
+var quotaHeaders = map[string]string{
+	"short": "Req-Limit-Short",
+	"long":  "Req-Limit-Long",
+}
+
+var (
+	apiRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "api_retries_total",
+		Help:      "Retried Pingdom API requests by operation.",
+	}, []string{"operation"})
+
+	apiQuotaRemaining = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "api_quota_remaining",
+		Help:      "Remaining Pingdom API requests by rate-limit window, as of the last response.",
+	}, []string{"window"})
+
+	apiQuotaReset = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "api_quota_reset_seconds",
+		Help:      "Seconds until the Pingdom API rate-limit window resets, as of the last response.",
+	}, []string{"window"})
+)
+
+func init() {
+	prometheus.MustRegister(apiRetries, apiQuotaRemaining, apiQuotaReset)
+}
+
+// retryingChecks spaces requests to Pingdom and retries requests failing
+// with 429 or 5xx responses with an exponential backoff. Requests failing
+// with other errors, e.g. network errors, are not retried as the check may
+// have been created. A check may also have been created before a 5xx
+// response, e.g. a gateway timeout, so it is looked up by its name before
+// creating it again.
+type retryingChecks struct {
+	checks  ChecksAPI
+	limiter flowcontrol.RateLimiter
+	backoff util.Backoff
+}
+
+// newRetryingChecks returns the checks API limited to qps requests per
+// second with the given burst, or unlimited if qps is zero.
+func newRetryingChecks(checks ChecksAPI, qps float32, burst, retries int) *retryingChecks {
+	limiter := flowcontrol.NewFakeAlwaysRateLimiter()
+	if qps > 0 {
+		limiter = flowcontrol.NewTokenBucketRateLimiter(qps, burst)
+	}
+	backoff := defaultAPIBackoff
+	backoff.Retries = retries
+	return &retryingChecks{checks: checks, limiter: limiter, backoff: backoff}
+}
+
+// do calls f until it succeeds, fails with an error which is not retried
+// or the retries are used up. Returns the last error of f.
+func (r *retryingChecks) do(operation string, f func() error) error {
+	var err error
+	var n int
+	util.RetryBackoff(r.backoff, func() (bool, error) {
+		if n > 0 {
+			apiRetries.WithLabelValues(operation).Inc()
+			log.WithFields(logrus.Fields{"operation": operation, "retry": n}).WithError(err).Debug("Retrying Pingdom API request")
+		}
+		n++
+		r.limiter.Accept()
+		err = f()
+		return !retryable(err), nil
+	})
+	return err
+}
+
+func (r *retryingChecks) List(params ...map[string]string) (list []pdom.CheckResponse, err error) {
+	err = r.do("listChecks", func() error {
+		list, err = r.checks.List(params...)
+		return err
+	})
+	return list, err
+}
+
+func (r *retryingChecks) Create(check pdom.Check) (resp *pdom.CheckResponse, err error) {
+	var lookup bool
+	err = r.do("createCheck", func() error {
+		if lookup {
+			resp, err = r.findCheck(checkResponse(0, check).Name)
+			if err != nil || resp != nil {
+				return err
+			}
+		}
+		resp, err = r.checks.Create(check)
+		lookup = serverError(err)
+		return err
+	})
+	return resp, err
+}
+
+// Returns the check with the name, nil if there is none. Check names are
+// unique, they include the owner of the check.
+func (r *retryingChecks) findCheck(name string) (*pdom.CheckResponse, error) {
+	r.limiter.Accept()
+	list, err := r.checks.List()
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		if list[i].Name == name {
+			log.WithFields(logrus.Fields{"check_id": list[i].ID, "check_name": name}).Info("Found check created by a failed request")
+			return &list[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *retryingChecks) Read(id int) (resp *pdom.CheckResponse, err error) {
+	err = r.do("readCheck", func() error {
+		resp, err = r.checks.Read(id)
+		return err
+	})
+	return resp, err
+}
+
+func (r *retryingChecks) Update(id int, check pdom.Check) (resp *pdom.PingdomResponse, err error) {
+	err = r.do("updateCheck", func() error {
+		resp, err = r.checks.Update(id, check)
+		return err
+	})
+	return resp, err
+}
+
+func (r *retryingChecks) Delete(id int) (resp *pdom.PingdomResponse, err error) {
+	err = r.do("deleteCheck", func() error {
+		resp, err = r.checks.Delete(id)
+		return err
+	})
+	return resp, err
+}
+
+// Returns true if the request was rate limited or failed in Pingdom.
+func retryable(err error) bool {
+	perr, ok := err.(*pdom.PingdomError)
+	if !ok {
+		return false
+	}
+	return perr.StatusCode == http.StatusTooManyRequests || serverError(err)
+}
+
+// Returns true if the request failed in Pingdom. 429 responses, including
+// those of QuotaTransport, are rejected before the request takes effect.
+func serverError(err error) bool {
+	perr, ok := err.(*pdom.PingdomError)
+	return ok && perr.StatusCode >= 500
+}
+
+// QuotaTransport records the rate-limit headers of Pingdom responses in
+// metrics. Once the requests of a window are used up, requests fail with a
+// 429 response until the window resets, without being sent. They are
+// retried like requests rate limited by Pingdom, and then by the queue,
+// instead of blocking the workers until the reset.
+type QuotaTransport struct {
+	transport http.RoundTripper
+
+	mux          sync.Mutex
+	blockedUntil time.Time
+}
+
+// NewQuotaTransport wraps the transport, http.DefaultTransport if nil.
+func NewQuotaTransport(transport http.RoundTripper) *QuotaTransport {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &QuotaTransport{transport: transport}
+}
+
+func (t *QuotaTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mux.Lock()
+	wait := t.blockedUntil.Sub(time.Now())
+	t.mux.Unlock()
+	if wait > 0 {
+		log.WithField("wait", wait).Warning("Pingdom API quota used up, failing requests until reset")
+		return quotaResponse(req, wait), nil
+	}
+
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	for window, header := range quotaHeaders {
+		v := resp.Header.Get(header)
+		if v == "" {
+			continue
+		}
+		remaining, reset, err := parseQuota(v)
+		if err != nil {
+			log.WithField("header", header).WithError(err).Debug("Error parsing rate-limit header")
+			continue
+		}
+		apiQuotaRemaining.WithLabelValues(window).Set(float64(remaining))
+		apiQuotaReset.WithLabelValues(window).Set(reset.Seconds())
+
+		if remaining == 0 {
+			t.mux.Lock()
+			if until := time.Now().Add(reset); until.After(t.blockedUntil) {
+				t.blockedUntil = until
+			}
+			t.mux.Unlock()
+		}
+	}
+	return resp, nil
+}
+
+// Returns a 429 response in the error format of both Pingdom API versions.
+func quotaResponse(req *http.Request, wait time.Duration) *http.Response {
+	body, _ := json.Marshal(map[string]interface{}{
+		"error": map[string]interface{}{
+			"statuscode":   http.StatusTooManyRequests,
+			"statusdesc":   http.StatusText(http.StatusTooManyRequests),
+			"errormessage": fmt.Sprintf("API quota used up, resets in %v", wait),
+		},
+	})
+	header := make(http.Header)
+	header.Set("Content-Type", "application/json")
+	header.Set("Retry-After", strconv.Itoa(int(wait.Seconds()+1)))
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", http.StatusTooManyRequests, http.StatusText(http.StatusTooManyRequests)),
+		StatusCode:    http.StatusTooManyRequests,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// Parses a rate-limit header like "Remaining: 394 Time until reset: 3589".
+func parseQuota(v string) (remaining int, reset time.Duration, err error) {
+	var seconds int
+	if _, err := fmt.Sscanf(v, "Remaining: %d Time until reset: %d", &remaining, &seconds); err != nil {
+		return 0, 0, fmt.Errorf("invalid rate-limit header %q", v)
+	}
+	return remaining, time.Duration(seconds) * time.Second, nil
+}
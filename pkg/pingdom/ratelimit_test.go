@@ -0,0 +1,116 @@
+package pingdom
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	pdom "github.com/russellcardullo/go-pingdom/pingdom"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rossf7/pingdom-operator/pkg/pingdom/fake"
+)
+
+func TestRetryingChecks(t *testing.T) {
+	p := fake.New()
+	r := newRetryingChecks(p, 0, 0, 2)
+	r.backoff.Base, r.backoff.Max = time.Millisecond, time.Millisecond
+
+	// Rate limited creates are retried without looking up the check.
+	p.Fail(fake.OpCreate, 2, &pdom.PingdomError{StatusCode: 429, StatusDesc: "Too Many Requests"})
+	_, err := r.Create(&pdom.HttpCheck{Name: "test", Hostname: "test.example.com"})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, p.Calls(fake.OpCreate))
+	assert.Equal(t, 0, p.Calls(fake.OpList))
+
+	p.Fail(fake.OpList, -1, &pdom.PingdomError{StatusCode: 429, StatusDesc: "Too Many Requests"})
+	_, err = r.List()
+	assert.EqualError(t, err, "429 Too Many Requests: ")
+	assert.Equal(t, 3, p.Calls(fake.OpList))
+
+	// Client and network errors are not retried.
+	_, err = r.Read(42)
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, p.Calls(fake.OpRead))
+	p.Fail(fake.OpDelete, -1, errors.New("connection reset"))
+	_, err = r.Delete(1)
+	assert.EqualError(t, err, "connection reset")
+	assert.Equal(t, 1, p.Calls(fake.OpDelete))
+}
+
+func TestRetryingChecksCreateCommitted(t *testing.T) {
+	p := fake.New()
+	r := newRetryingChecks(p, 0, 0, 2)
+	r.backoff.Base, r.backoff.Max = time.Millisecond, time.Millisecond
+
+	// The check is created although the gateway timed out.
+	p.FailCommitted(fake.OpCreate, 1, &pdom.PingdomError{StatusCode: 502, StatusDesc: "Bad Gateway"})
+	resp, err := r.Create(&pdom.HttpCheck{Name: "[default/default/pets] cats.example.com", Hostname: "cats.example.com"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, p.Calls(fake.OpCreate))
+	assert.Equal(t, 1, p.Calls(fake.OpList))
+	assert.Equal(t, []string{"cats.example.com"}, p.Hosts())
+	if assert.NotNil(t, resp) {
+		_, ok := p.Checks()[resp.ID]
+		assert.True(t, ok)
+	}
+
+	// The check is created again if the failed request did not create it.
+	p.Fail(fake.OpCreate, 1, &pdom.PingdomError{StatusCode: 502, StatusDesc: "Bad Gateway"})
+	_, err = r.Create(&pdom.HttpCheck{Name: "[default/default/pets] dogs.example.com", Hostname: "dogs.example.com"})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, p.Calls(fake.OpCreate))
+	assert.Equal(t, []string{"cats.example.com", "dogs.example.com"}, p.Hosts())
+}
+
+func TestParseQuota(t *testing.T) {
+	remaining, reset, err := parseQuota("Remaining: 394 Time until reset: 3589")
+	assert.Nil(t, err)
+	assert.Equal(t, 394, remaining)
+	assert.Equal(t, 3589*time.Second, reset)
+
+	_, _, err = parseQuota("unlimited")
+	assert.NotNil(t, err)
+}
+
+func TestQuotaTransport(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Req-Limit-Short", "Remaining: 0 Time until reset: 60")
+		w.Header().Set("Req-Limit-Long", "Remaining: 71940 Time until reset: 2591999")
+	}))
+	defer srv.Close()
+
+	tr := NewQuotaTransport(nil)
+	client := &http.Client{Transport: tr}
+	resp, err := client.Get(srv.URL)
+	assert.Nil(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, float64(0), gaugeValue(t, apiQuotaRemaining.WithLabelValues("short")))
+	assert.Equal(t, float64(71940), gaugeValue(t, apiQuotaRemaining.WithLabelValues("long")))
+	assert.Equal(t, float64(60), gaugeValue(t, apiQuotaReset.WithLabelValues("short")))
+
+	// Further requests fail until the short window resets, without waiting.
+	wait := tr.blockedUntil.Sub(time.Now())
+	assert.True(t, wait > 55*time.Second && wait <= 60*time.Second, "%v", wait)
+
+	c := NewChecksV31("secret", client).(*checksV31)
+	c.baseURL = srv.URL
+	_, err = c.List()
+	assert.True(t, retryable(err), "%v", err)
+	assert.Equal(t, 1, requests)
+}
+
+func gaugeValue(t *testing.T, g prometheus.Gauge) float64 {
+	m := &dto.Metric{}
+	if err := g.Write(m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetGauge().GetValue()
+}
@@ -0,0 +1,214 @@
+package pingdom
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/client-go/pkg/api/errors"
+	"k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/pkg/apis/extensions/v1beta1"
+	utilerrors "k8s.io/client-go/pkg/util/errors"
+)
+
+// Converges the Pingdom checks of the Ingress to the desired state: a check
+// for each host using the spec referenced by the annotation, or no checks
+// if the Ingress is not annotated. The actual state is read from Pingdom so
+// checks which failed to be created or were removed in Pingdom are fixed.
+func (o *Operator) reconcile(logger *logrus.Entry, ing *v1beta1.Ingress) error {
+	// Get a fresh copy, events may be stale.
+	ing, err := o.kclient.ExtensionsV1beta1().Ingresses(ing.Namespace).Get(ing.Name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("getting ingress: %v", err)
+	}
+
+	if ing.ObjectMeta.DeletionTimestamp != nil {
+		return o.finalize(logger, ing)
+	}
+
+	checks, err := o.getChecks(ing)
+	if err != nil {
+		return err
+	}
+
+	checkName, ok := o.annotation(ing)
+	if !ok && len(checks) == 0 {
+		if hasFinalizer(ing) {
+			return o.setChecksAnnotation(ing, checks)
+		}
+		return nil
+	}
+
+	// Add the finalizer before creating any checks.
+	if ok && !hasFinalizer(ing) {
+		err := o.setChecksAnnotation(ing, checks)
+		if err != nil {
+			return err
+		}
+	}
+
+	existing, err := o.listChecks()
+	if err != nil {
+		return fmt.Errorf("listing checks: %v", err)
+	}
+
+	var hosts []string
+	if ok {
+		hosts = getIngressHosts(ing)
+	}
+	checkSpec := o.checkSpec(ing.Namespace, checkName)
+	ref := ingressReference(ing)
+
+	// Checks are updated if the spec changed since they were last updated,
+	// e.g. when the annotation names another Check.
+	hash, err := o.specHash(ing.Namespace, checkSpec)
+	if err != nil {
+		logger.WithError(err).Error("Error hashing check spec")
+	}
+	stale := ok && (hash == "" || ing.ObjectMeta.Annotations[o.specAnnotation()] != hash)
+
+	var errs []error
+
+	// Checks missing in Pingdom are dropped so they are created again.
+	current := make(map[string]int)
+	for host, id := range checks {
+		o.checks.Forget(id)
+		if _, ok := existing[id]; !ok {
+			logger.WithFields(logrus.Fields{"host": host, "check_id": id}).Debug("Check not found in Pingdom")
+			continue
+		}
+		current[host] = id
+		o.checks.Add(ing.Namespace, checkName, id)
+	}
+
+	// Hosts without a check in the annotation adopt the check with their
+	// name, e.g. if saving the annotation failed after creating it, instead
+	// of creating another one.
+	owner := o.owner(ing)
+	for _, host := range hosts {
+		if _, ok := current[host]; ok {
+			continue
+		}
+		name := owner.checkName(host)
+		for id, r := range existing {
+			if r.Name == name {
+				logger.WithFields(logrus.Fields{"host": host, "check_id": id}).Info("Adopting Pingdom check missing in the annotation")
+				current[host] = id
+				o.checks.Add(ing.Namespace, checkName, id)
+				break
+			}
+		}
+	}
+
+	// Delete checks of hosts which are gone and update the ones which
+	// differ from the spec. Deleted checks of hosts which are kept are
+	// created again below.
+	removed := make(map[string]int)
+	for host, id := range current {
+		if !containsHost(hosts, host) {
+			removed[host] = id
+			continue
+		}
+		// The type of a check can't be changed, it is deleted and created
+		// again.
+		if typeChanged(existing[id], checkSpec) {
+			logger.WithFields(logrus.Fields{"host": host, "check_id": id}).Debugf("Replacing check with a %s check", checkSpec.CheckType())
+			removed[host] = id
+			continue
+		}
+		if !stale && checkMatches(existing[id], host, checkSpec) {
+			continue
+		}
+		err := o.updateCheck(ing.Namespace, id, checkSpec)
+		if err == nil {
+			logger.WithFields(logrus.Fields{"host": host, "check_id": id}).Debug("Updated Pingdom check")
+			o.recordCheckEvent(ref, ing.Namespace, checkName, v1.EventTypeNormal, reasonCheckUpdated, "Updated Pingdom check %d for host %s", id, host)
+		} else {
+			errs = append(errs, fmt.Errorf("updating check %d for host %s: %v", id, host, err))
+			o.recordCheckEvent(ref, ing.Namespace, checkName, v1.EventTypeWarning, reasonUpdateCheckFailed, "Error updating Pingdom check %d for host %s: %v", id, host, err)
+		}
+	}
+
+	for host := range removed {
+		delete(current, host)
+	}
+	left := o.deleteChecks(logger, ref, removed, checkName)
+	for host, id := range left {
+		current[host] = id
+		errs = append(errs, fmt.Errorf("deleting check %d for host %s", id, host))
+	}
+
+	if !reflect.DeepEqual(current, checks) {
+		err := o.setChecksAnnotation(ing, current)
+		if err != nil {
+			errs = append(errs, err)
+			return utilerrors.NewAggregate(errs)
+		}
+	}
+
+	var added []string
+	for _, host := range hosts {
+		if _, ok := current[host]; !ok {
+			added = append(added, host)
+		}
+	}
+	if len(added) > 0 {
+		err := o.createChecks(logger, ing, added, checkName, checkSpec)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if stale && hash != "" && len(errs) == 0 {
+		if err := o.setSpecAnnotation(ing, hash); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// Deletes all checks of the Ingress being deleted. The finalizer is removed
+// once every check listed in the annotation is deleted.
+func (o *Operator) finalize(logger *logrus.Entry, ing *v1beta1.Ingress) error {
+	if !hasFinalizer(ing) {
+		return nil
+	}
+
+	checks, err := o.getChecks(ing)
+	if err != nil {
+		// There is nothing that could be deleted, don't block the deletion.
+		logger.WithError(err).Error("Error getting checks of ingress")
+		checks = nil
+	}
+
+	existing, err := o.listChecks()
+	if err != nil {
+		return fmt.Errorf("listing checks: %v", err)
+	}
+
+	// Checks already gone from Pingdom don't need deleting.
+	for host, id := range checks {
+		if _, ok := existing[id]; !ok {
+			o.checks.Forget(id)
+			delete(checks, host)
+		}
+	}
+
+	checkName, _ := o.annotation(ing)
+	left := o.deleteChecks(logger, ingressReference(ing), checks, checkName)
+
+	err = o.setChecksAnnotation(ing, left)
+	if err != nil {
+		return err
+	}
+
+	if len(left) > 0 {
+		return fmt.Errorf("failed to delete %d checks", len(left))
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package pingdom
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/rossf7/pingdom-operator/pkg/tpr"
+
+	"k8s.io/client-go/pkg/api/unversioned"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+// Writes the status of the Check after its spec was propagated to the
+// Pingdom checks. syncErr is the error of propagating the spec.
+func (o *Operator) updateCheckStatus(namespace, name string, checkSpec tpr.Spec, syncErr error) error {
+	check, err := o.checkClient.Checks(namespace).Get(name)
+	if err != nil {
+		return err
+	}
+
+	status := check.Status
+	status.LastSyncTime = unversioned.Now()
+	status.Ingresses = o.checkIngresses(namespace, name)
+	// The Check may have changed since the event was queued.
+	if reflect.DeepEqual(check.Spec, checkSpec) {
+		status.ObservedGeneration = check.Generation
+	}
+
+	cond := tpr.Condition{
+		Type:               tpr.ConditionReady,
+		Status:             v1.ConditionTrue,
+		LastTransitionTime: status.LastSyncTime,
+		Reason:             "Synced",
+	}
+	if syncErr != nil {
+		cond.Status = v1.ConditionFalse
+		cond.Reason = "Error"
+		cond.Message = syncErr.Error()
+	}
+	status.SetCondition(cond)
+
+	check.Status = status
+	_, err = o.checkClient.Checks(namespace).UpdateStatus(check)
+	return err
+}
+
+// Returns the Ingresses in the namespace referencing the Check and their
+// Pingdom checks, sorted by name.
+func (o *Operator) checkIngresses(namespace, name string) []tpr.IngressStatus {
+	var ingresses []tpr.IngressStatus
+	for _, ing := range o.listIngresses() {
+		if checkName, ok := o.annotation(ing); !ok || ing.Namespace != namespace || checkName != name {
+			continue
+		}
+
+		checks, err := o.getChecks(ing)
+		if err != nil {
+			log.WithFields(ingressFields(ing)).WithError(err).Error("Error getting checks of ingress")
+		}
+		ingresses = append(ingresses, tpr.IngressStatus{Name: ing.Name, Checks: checks})
+	}
+
+	sort.Sort(byIngressName(ingresses))
+	return ingresses
+}
+
+type byIngressName []tpr.IngressStatus
+
+func (s byIngressName) Len() int           { return len(s) }
+func (s byIngressName) Less(i, j int) bool { return s[i].Name < s[j].Name }
+func (s byIngressName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,625 @@
+package pingdom
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	pdom "github.com/russellcardullo/go-pingdom/pingdom"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rossf7/pingdom-operator/pkg/pingdom/fake"
+	"github.com/rossf7/pingdom-operator/pkg/tpr"
+
+	"k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/pkg/apis/extensions/v1beta1"
+	"k8s.io/client-go/pkg/runtime"
+	core "k8s.io/client-go/testing"
+)
+
+func newIngress(namespace, name, checkName string, hosts ...string) *v1beta1.Ingress {
+	ing := &v1beta1.Ingress{
+		ObjectMeta: v1.ObjectMeta{
+			Namespace:   namespace,
+			Name:        name,
+			Annotations: map[string]string{DefaultAnnotation: checkName},
+		},
+	}
+	for _, host := range hosts {
+		ing.Spec.Rules = append(ing.Spec.Rules, v1beta1.IngressRule{Host: host})
+	}
+	return ing
+}
+
+func newCheckResponse(name, host string) pdom.CheckResponse {
+	return pdom.CheckResponse{Name: name, Hostname: host, Resolution: 1, Status: "up"}
+}
+
+func newCheck(namespace, name string, spec tpr.Spec) *tpr.PingdomCheck {
+	return &tpr.PingdomCheck{
+		ObjectMeta: v1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       spec,
+	}
+}
+
+func TestSyncCreatesChecks(t *testing.T) {
+	h := newHarness(t, DefaultConfig())
+	defer h.stop()
+	h.createIngress(newIngress("default", "pets", "pets", "cats.example.com", "dogs.example.com"))
+	h.sync()
+
+	assert.Equal(t, []string{"cats.example.com", "dogs.example.com"}, h.pingdom.Hosts())
+	for _, r := range h.pingdom.Checks() {
+		assert.Equal(t, 1, r.Resolution)
+		assert.Equal(t, "[default/default/pets] "+r.Hostname, r.Name)
+	}
+
+	ing := h.ingress("default", "pets")
+	checks, err := h.o.getChecks(ing)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(checks))
+	assert.True(t, hasFinalizer(ing))
+
+	// Syncing again changes nothing.
+	h.resync()
+	h.sync()
+	assert.Equal(t, 2, h.pingdom.Calls(fake.OpCreate))
+	assert.Equal(t, 0, h.pingdom.Calls(fake.OpUpdate))
+}
+
+func TestSyncIgnoresIngressWithoutAnnotation(t *testing.T) {
+	h := newHarness(t, DefaultConfig())
+	defer h.stop()
+	ing := newIngress("default", "pets", "", "cats.example.com")
+	ing.Annotations = nil
+	h.createIngress(ing)
+	h.sync()
+
+	assert.Equal(t, 0, len(h.pingdom.Checks()))
+	assert.Equal(t, 0, h.pingdom.Calls(fake.OpList))
+}
+
+func TestSyncCheckSpec(t *testing.T) {
+	h := newHarness(t, DefaultConfig())
+	defer h.stop()
+	h.createCheck(newCheck("default", "pets", tpr.Spec{Resolution: 5}))
+	h.createIngress(newIngress("default", "pets", "pets", "cats.example.com", "dogs.example.com"))
+	h.sync()
+
+	for _, r := range h.pingdom.Checks() {
+		assert.Equal(t, 5, r.Resolution)
+	}
+
+	check := h.checks.get("default", "pets")
+	check.Spec.Resolution = 15
+	h.updateCheck(check)
+	h.sync()
+
+	for _, r := range h.pingdom.Checks() {
+		assert.Equal(t, 15, r.Resolution)
+	}
+	check = h.checks.get("default", "pets")
+	assert.Equal(t, int64(2), check.Status.ObservedGeneration)
+	assert.Equal(t, 1, len(check.Status.Ingresses))
+	assert.Equal(t, v1.ConditionTrue, check.Status.Conditions[0].Status)
+
+	// Checks fall back to the default spec when the Check is deleted.
+	h.deleteCheck("default", "pets")
+	h.sync()
+	for _, r := range h.pingdom.Checks() {
+		assert.Equal(t, 1, r.Resolution)
+	}
+}
+
+// Checks are updated when the annotation names another Check, even if the
+// listed fields of the checks match.
+func TestSyncAnnotationChange(t *testing.T) {
+	h := newHarness(t, DefaultConfig())
+	defer h.stop()
+	h.createCheck(newCheck("default", "a", tpr.Spec{Resolution: 5, URL: "/a"}))
+	h.createCheck(newCheck("default", "b", tpr.Spec{Resolution: 5, URL: "/b", Encryption: true}))
+	h.createIngress(newIngress("default", "pets", "a", "cats.example.com"))
+	h.sync()
+
+	ing := h.ingress("default", "pets")
+	ing.Annotations[DefaultAnnotation] = "b"
+	h.updateIngress(ing)
+	h.sync()
+
+	assert.Equal(t, 1, h.pingdom.Calls(fake.OpUpdate))
+	for _, r := range h.pingdom.Checks() {
+		assert.Equal(t, "/b", r.Type.HTTP.Url)
+		assert.True(t, r.Type.HTTP.Encryption)
+	}
+
+	// Checks are not updated again once they match.
+	h.resync()
+	h.sync()
+	assert.Equal(t, 1, h.pingdom.Calls(fake.OpUpdate))
+}
+
+// Changes of any field of the spec reach Pingdom through the reconcile
+// path, even if the Check change itself was missed.
+func TestSyncSpecFields(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		change func(*tpr.Spec)
+		assert func(*testing.T, pdom.CheckResponse)
+	}{
+		{
+			name:   "http request",
+			change: func(s *tpr.Spec) { s.URL, s.Encryption, s.Port = "/healthz", true, 8443 },
+			assert: func(t *testing.T, r pdom.CheckResponse) {
+				assert.Equal(t, "/healthz", r.Type.HTTP.Url)
+				assert.True(t, r.Type.HTTP.Encryption)
+				assert.Equal(t, 8443, r.Type.HTTP.Port)
+			},
+		},
+		{
+			name: "http content",
+			change: func(s *tpr.Spec) {
+				s.ShouldContain, s.ShouldNotContain = "ok", "error"
+				s.RequestHeaders = map[string]string{"X-Check": "pingdom"}
+			},
+			assert: func(t *testing.T, r pdom.CheckResponse) {
+				assert.Equal(t, "ok", r.Type.HTTP.ShouldContain)
+				assert.Equal(t, "error", r.Type.HTTP.ShouldNotContain)
+				assert.Equal(t, map[string]string{"X-Check": "pingdom"}, r.Type.HTTP.RequestHeaders)
+			},
+		},
+		{
+			name:   "notifications",
+			change: func(s *tpr.Spec) { s.NotifyAfterFailures, s.NotifyAgainEvery = 3, 10 },
+			assert: func(t *testing.T, r pdom.CheckResponse) {
+				assert.Equal(t, 3, r.SendNotificationWhenDown)
+				assert.Equal(t, 10, r.NotifyAgainEvery)
+			},
+		},
+		{
+			name:   "contacts",
+			change: func(s *tpr.Spec) { s.ContactIDs, s.IntegrationIDs = []int{1, 2}, []int{3} },
+			assert: func(t *testing.T, r pdom.CheckResponse) {
+				assert.Equal(t, []int{1, 2}, r.ContactIds)
+				assert.Equal(t, []int{3}, r.IntegrationIds)
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			h := newHarness(t, DefaultConfig())
+			defer h.stop()
+			h.createCheck(newCheck("default", "pets", tpr.Spec{Resolution: 5}))
+			h.createIngress(newIngress("default", "pets", "pets", "cats.example.com"))
+			h.sync()
+
+			// Only the store is updated, the Check is not queued.
+			check := h.checks.get("default", "pets")
+			test.change(&check.Spec)
+			h.informers("default").checkInf.GetStore().Update(check)
+			h.resync()
+			h.sync()
+
+			assert.Equal(t, 1, h.pingdom.Calls(fake.OpUpdate))
+			for _, r := range h.pingdom.Checks() {
+				test.assert(t, r)
+			}
+		})
+	}
+}
+
+func TestSyncBasicAuthSecret(t *testing.T) {
+	h := newHarness(t, DefaultConfig())
+	defer h.stop()
+	secret := &v1.Secret{
+		ObjectMeta: v1.ObjectMeta{Namespace: "default", Name: "pets-auth", ResourceVersion: "1"},
+		Data:       map[string][]byte{"username": []byte("cat"), "password": []byte("meow")},
+	}
+	if _, err := h.kclient.CoreV1().Secrets("default").Create(secret); err != nil {
+		t.Fatal(err)
+	}
+	h.createCheck(newCheck("default", "pets", tpr.Spec{BasicAuth: &tpr.BasicAuth{SecretName: "pets-auth"}}))
+	h.createIngress(newIngress("default", "pets", "pets", "cats.example.com"))
+	h.sync()
+
+	secret.ResourceVersion = "2"
+	secret.Data["password"] = []byte("purr")
+	if _, err := h.kclient.CoreV1().Secrets("default").Update(secret); err != nil {
+		t.Fatal(err)
+	}
+	h.resync()
+	h.sync()
+
+	assert.Equal(t, 1, h.pingdom.Calls(fake.OpUpdate))
+	for _, r := range h.pingdom.Checks() {
+		assert.Equal(t, "cat", r.Type.HTTP.Username)
+		assert.Equal(t, "purr", r.Type.HTTP.Password)
+	}
+}
+
+// Checks of the same name in other namespaces are independent.
+func TestSyncCheckSpecNamespaces(t *testing.T) {
+	h := newHarness(t, DefaultConfig())
+	defer h.stop()
+	h.createCheck(newCheck("team-a", "web", tpr.Spec{Resolution: 5}))
+	h.createCheck(newCheck("team-b", "web", tpr.Spec{Resolution: 10}))
+	h.createIngress(newIngress("team-a", "web", "web", "a.example.com"))
+	h.createIngress(newIngress("team-b", "web", "web", "b.example.com"))
+	h.sync()
+
+	check := h.checks.get("team-a", "web")
+	check.Spec.Resolution = 15
+	h.updateCheck(check)
+	h.sync()
+	assert.Equal(t, 1, h.pingdom.Calls(fake.OpUpdate))
+
+	resolutions := make(map[string]int)
+	for _, r := range h.pingdom.Checks() {
+		resolutions[r.Hostname] = r.Resolution
+	}
+	assert.Equal(t, map[string]int{"a.example.com": 15, "b.example.com": 10}, resolutions)
+
+	// Resyncs don't flap the checks between the specs.
+	h.resync()
+	h.sync()
+	assert.Equal(t, 1, h.pingdom.Calls(fake.OpUpdate))
+}
+
+func TestSyncCheckTypeChange(t *testing.T) {
+	h := newHarness(t, DefaultConfig())
+	defer h.stop()
+	h.createCheck(newCheck("default", "pets", tpr.Spec{Resolution: 5}))
+	h.createIngress(newIngress("default", "pets", "pets", "cats.example.com"))
+	h.sync()
+
+	check := h.checks.get("default", "pets")
+	check.Spec.Type = tpr.CheckTypePing
+	h.updateCheck(check)
+	h.sync()
+
+	checks := h.pingdom.Checks()
+	assert.Equal(t, 1, len(checks))
+	for id, r := range checks {
+		assert.Equal(t, tpr.CheckTypePing, r.Type.Name)
+		ingChecks, _ := h.o.getChecks(h.ingress("default", "pets"))
+		assert.Equal(t, map[string]int{"cats.example.com": id}, ingChecks)
+	}
+}
+
+func TestSyncHostChanges(t *testing.T) {
+	h := newHarness(t, DefaultConfig())
+	defer h.stop()
+	h.createIngress(newIngress("default", "pets", "pets", "cats.example.com", "dogs.example.com"))
+	h.sync()
+
+	ing := h.ingress("default", "pets")
+	ing.Spec.Rules = []v1beta1.IngressRule{{Host: "dogs.example.com"}, {Host: "ants.example.com"}}
+	h.updateIngress(ing)
+	h.sync()
+
+	assert.Equal(t, []string{"ants.example.com", "dogs.example.com"}, h.pingdom.Hosts())
+
+	// Removing the annotation deletes the checks and the finalizer.
+	ing = h.ingress("default", "pets")
+	delete(ing.Annotations, DefaultAnnotation)
+	h.updateIngress(ing)
+	h.sync()
+
+	assert.Equal(t, 0, len(h.pingdom.Checks()))
+	ing = h.ingress("default", "pets")
+	assert.False(t, h.o.hasChecks(ing))
+	assert.False(t, hasFinalizer(ing))
+}
+
+func TestSyncDeleteIngress(t *testing.T) {
+	h := newHarness(t, DefaultConfig())
+	defer h.stop()
+	h.createIngress(newIngress("default", "pets", "pets", "cats.example.com"))
+	h.createIngress(newIngress("default", "ants", "ants", "ants.example.com"))
+	h.sync()
+
+	h.deleteIngress("default", "pets")
+	h.sync()
+
+	assert.Equal(t, []string{"ants.example.com"}, h.pingdom.Hosts())
+	_, err := h.kclient.ExtensionsV1beta1().Ingresses("default").Get("pets")
+	assert.NotNil(t, err)
+}
+
+func TestSyncDeleteIngressFailure(t *testing.T) {
+	h := newHarness(t, DefaultConfig())
+	defer h.stop()
+	h.createIngress(newIngress("default", "pets", "pets", "cats.example.com"))
+	h.sync()
+
+	// The Ingress is kept by the finalizer until its checks are deleted.
+	h.pingdom.Fail(fake.OpDelete, 1, errors.New("connection reset"))
+	h.deleteIngress("default", "pets")
+	h.sync()
+
+	assert.Equal(t, 1, len(h.pingdom.Checks()))
+	assert.True(t, hasFinalizer(h.ingress("default", "pets")))
+
+	h.resync()
+	h.sync()
+	assert.Equal(t, 0, len(h.pingdom.Checks()))
+	_, err := h.kclient.ExtensionsV1beta1().Ingresses("default").Get("pets")
+	assert.NotNil(t, err)
+}
+
+func TestSyncCreateFailure(t *testing.T) {
+	h := newHarness(t, DefaultConfig())
+	defer h.stop()
+	h.pingdom.Fail(fake.OpCreate, 1, errors.New("connection reset"))
+	h.createIngress(newIngress("default", "pets", "pets", "cats.example.com", "dogs.example.com"))
+	h.sync()
+
+	// The check created before the failure is saved, and saving it queues
+	// the Ingress again to create the other check.
+	assert.Equal(t, []string{"cats.example.com", "dogs.example.com"}, h.pingdom.Hosts())
+	assert.Equal(t, 3, h.pingdom.Calls(fake.OpCreate))
+	checks, _ := h.o.getChecks(h.ingress("default", "pets"))
+	assert.Equal(t, 2, len(checks))
+}
+
+func TestSyncAnnotationUpdateFailure(t *testing.T) {
+	h := newHarness(t, DefaultConfig())
+	defer h.stop()
+	var failed bool
+	h.kclient.PrependReactor("update", "ingresses", func(action core.Action) (bool, runtime.Object, error) {
+		ing := action.(core.UpdateAction).GetObject().(*v1beta1.Ingress)
+		if _, ok := ing.Annotations[DefaultChecksAnnotation]; ok && !failed {
+			failed = true
+			return true, nil, errors.New("conflict")
+		}
+		return false, nil, nil
+	})
+	h.createIngress(newIngress("default", "pets", "pets", "cats.example.com"))
+	h.sync()
+	assert.True(t, failed)
+
+	// The check created before the failure is adopted instead of creating
+	// another one.
+	h.resync()
+	h.sync()
+	assert.Equal(t, []string{"cats.example.com"}, h.pingdom.Hosts())
+	assert.Equal(t, 1, h.pingdom.Calls(fake.OpCreate))
+	checks, _ := h.o.getChecks(h.ingress("default", "pets"))
+	for _, id := range checks {
+		_, ok := h.pingdom.Checks()[id]
+		assert.True(t, ok)
+	}
+	assert.Equal(t, 1, len(checks))
+}
+
+func TestSyncRecreatesMissingChecks(t *testing.T) {
+	h := newHarness(t, DefaultConfig())
+	defer h.stop()
+	h.createIngress(newIngress("default", "pets", "pets", "cats.example.com"))
+	h.sync()
+
+	for id := range h.pingdom.Checks() {
+		h.pingdom.Delete(id)
+	}
+	h.resync()
+	h.sync()
+
+	assert.Equal(t, []string{"cats.example.com"}, h.pingdom.Hosts())
+}
+
+func TestSyncListFailure(t *testing.T) {
+	h := newHarness(t, DefaultConfig())
+	defer h.stop()
+	h.pingdom.Fail(fake.OpList, -1, errors.New("service unavailable"))
+	h.createIngress(newIngress("default", "pets", "pets", "cats.example.com"))
+	h.sync()
+
+	assert.Equal(t, 0, len(h.pingdom.Checks()))
+	assert.NotNil(t, h.o.Ready())
+	assert.Equal(t, 0, h.pingdom.Calls(fake.OpCreate))
+
+	h.pingdom.Fail(fake.OpList, 0, nil)
+	h.resync()
+	h.sync()
+	assert.Equal(t, []string{"cats.example.com"}, h.pingdom.Hosts())
+}
+
+func TestSyncNamespaces(t *testing.T) {
+	config := DefaultConfig()
+	config.Namespaces = []string{"team-a"}
+	h := newHarness(t, config)
+	defer h.stop()
+	h.createIngress(newIngress("team-a", "pets", "pets", "cats.example.com"))
+	h.sync()
+
+	// Checks of other namespaces are not collected as garbage.
+	h.pingdom.Add(newCheckResponse("[default/team-b/pets] dogs.example.com", "dogs.example.com"))
+	h.pingdom.Add(newCheckResponse("[default/team-a/ants] ants.example.com", "ants.example.com"))
+	h.o.collectGarbage()
+
+	assert.Equal(t, []string{"cats.example.com", "dogs.example.com"}, h.pingdom.Hosts())
+}
+
+func TestSyncDryRun(t *testing.T) {
+	config := DefaultConfig()
+	config.DryRun = true
+	h := newHarness(t, config)
+	defer h.stop()
+	h.createIngress(newIngress("default", "pets", "pets", "cats.example.com"))
+	h.sync()
+
+	assert.Equal(t, 0, len(h.pingdom.Checks()))
+	assert.Equal(t, 0, h.pingdom.Calls(fake.OpCreate))
+
+	ing := h.ingress("default", "pets")
+	assert.False(t, hasFinalizer(ing))
+	assert.Equal(t, "", ing.Annotations[DefaultChecksAnnotation])
+	assert.Equal(t, `{"cats.example.com":-1}`, ing.Annotations[DefaultDryRunAnnotation])
+
+	// The planned check is not created again.
+	h.resync()
+	h.sync()
+	assert.Equal(t, `{"cats.example.com":-1}`, h.ingress("default", "pets").Annotations[DefaultDryRunAnnotation])
+}
+
+func TestSyncEvents(t *testing.T) {
+	h := newHarness(t, DefaultConfig())
+	defer h.stop()
+	h.createCheck(newCheck("default", "pets", tpr.Spec{Resolution: 5}))
+	h.pingdom.Fail(fake.OpCreate, -1, &pdom.PingdomError{StatusCode: 400, StatusDesc: "Bad Request", Message: "Invalid hostname"})
+	h.createIngress(newIngress("default", "pets", "pets", "cats.example.com"))
+	h.sync()
+
+	events := h.recordedEvents()
+	assert.Contains(t, events, "Warning CreateCheckFailed Error creating Pingdom check for host cats.example.com: 400 Bad Request: Invalid hostname")
+	assert.Contains(t, events, "Warning CreateCheckFailed Ingress pets: Error creating Pingdom check for host cats.example.com: 400 Bad Request: Invalid hostname")
+
+	h.pingdom.Fail(fake.OpCreate, 0, nil)
+	h.resync()
+	h.sync()
+	assert.Equal(t, []string{
+		"Normal CheckCreated Created Pingdom check 1 for host cats.example.com",
+		"Normal CheckCreated Ingress pets: Created Pingdom check 1 for host cats.example.com",
+	}, h.recordedEvents())
+
+	check := h.checks.get("default", "pets")
+	check.Spec.Resolution = 15
+	h.updateCheck(check)
+	h.sync()
+	assert.Equal(t, []string{"Normal CheckUpdated Updated Pingdom check 1"}, h.recordedEvents())
+
+	h.deleteIngress("default", "pets")
+	h.sync()
+	assert.Equal(t, []string{
+		"Normal CheckDeleted Deleted Pingdom check 1 for host cats.example.com",
+		"Normal CheckDeleted Ingress pets: Deleted Pingdom check 1 for host cats.example.com",
+	}, h.recordedEvents())
+}
+
+func TestSyncDeletedIngressDropped(t *testing.T) {
+	h := newHarness(t, DefaultConfig())
+	defer h.stop()
+	h.createIngress(newIngress("default", "pets", "pets", "cats.example.com"))
+	h.sync()
+
+	// Deleted without the finalizer, e.g. removed by a user.
+	ing := h.ingress("default", "pets")
+	ing.Finalizers = nil
+	if _, err := h.kclient.ExtensionsV1beta1().Ingresses("default").Update(ing); err != nil {
+		t.Fatal(err)
+	}
+	h.informers("default").ingInf.GetStore().Update(ing)
+	h.pingdom.Fail(fake.OpDelete, -1, errors.New("connection reset"))
+	h.deleteIngress("default", "pets")
+	h.sync()
+
+	// The key is dropped after maxRetries.
+	h.o.queue.Forget(ingressKeyPrefix + "default/pets")
+	assert.Equal(t, 1, len(h.pingdom.Checks()))
+
+	h.pingdom.Fail(fake.OpDelete, 0, nil)
+	h.o.enqueueDeleted()
+	h.sync()
+	assert.Equal(t, 0, len(h.pingdom.Checks()))
+	assert.Equal(t, 0, len(h.o.deleted))
+}
+
+func TestRunWaitsForWorkers(t *testing.T) {
+	config := DefaultConfig()
+	config.Workers = 1
+	h := newHarness(t, config)
+	defer h.stop()
+	started := make(chan struct{})
+	var synced bool
+	h.kclient.PrependReactor("update", "ingresses", func(action core.Action) (bool, runtime.Object, error) {
+		if !synced {
+			synced = true
+			close(started)
+			time.Sleep(50 * time.Millisecond)
+		}
+		return false, nil, nil
+	})
+	h.createIngress(newIngress("default", "pets", "pets", "cats.example.com"))
+	h.createIngress(newIngress("default", "birds", "birds", "birds.example.com"))
+
+	stopc := make(chan struct{})
+	done := make(chan error)
+	go func() { done <- h.o.Run(stopc) }()
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("ingress not synced")
+	}
+	close(stopc)
+	assert.Nil(t, <-done)
+
+	// The Ingress being synced is finished, the queued one is left.
+	assert.Equal(t, []string{"cats.example.com"}, h.pingdom.Hosts())
+}
+
+// Returns the Ingress annotated with checks created before the operator
+// started.
+func newIngressWithChecks(namespace, name, checkName string, checks map[string]int) *v1beta1.Ingress {
+	var hosts []string
+	for host := range checks {
+		hosts = append(hosts, host)
+	}
+	ing := newIngress(namespace, name, checkName, hosts...)
+	data, _ := json.Marshal(checks)
+	ing.Annotations[DefaultChecksAnnotation] = string(data)
+	ing.Finalizers = []string{checksFinalizer}
+	return ing
+}
+
+func TestRebuildChecks(t *testing.T) {
+	h := newHarness(t, DefaultConfig())
+	defer h.stop()
+	cats := h.pingdom.Add(newCheckResponse("[default/default/pets] cats.example.com", "cats.example.com"))
+	birds := h.pingdom.Add(newCheckResponse("[default/default/birds] birds.example.com", "birds.example.com"))
+	// The check of dogs was deleted in Pingdom while the operator was down.
+	h.addIngress(newIngressWithChecks("default", "pets", "pets", map[string]int{"cats.example.com": cats, "dogs.example.com": 42}))
+	h.addIngress(newIngressWithChecks("default", "birds", "birds", map[string]int{"birds.example.com": birds}))
+	h.addIngress(newIngress("default", "fish", "fish", "fish.example.com"))
+
+	h.o.rebuildChecks()
+	assert.Equal(t, []int{cats}, h.o.checks.Get("default", "pets"))
+	assert.Equal(t, []int{birds}, h.o.checks.Get("default", "birds"))
+	assert.Equal(t, 0, len(h.o.checks.Get("default", "fish")))
+}
+
+func TestRebuildChecksListFailure(t *testing.T) {
+	h := newHarness(t, DefaultConfig())
+	defer h.stop()
+	cats := h.pingdom.Add(newCheckResponse("[default/default/pets] cats.example.com", "cats.example.com"))
+	h.addIngress(newIngressWithChecks("default", "pets", "pets", map[string]int{"cats.example.com": cats, "dogs.example.com": 42}))
+	h.pingdom.Fail(fake.OpList, -1, errors.New("service unavailable"))
+
+	// All checks of the annotation are used, missing checks are dropped
+	// when the Ingress is reconciled.
+	h.o.rebuildChecks()
+	ids := h.o.checks.Get("default", "pets")
+	sort.Ints(ids)
+	assert.Equal(t, []int{cats, 42}, ids)
+}
+
+func TestRebuildChecksSpecChange(t *testing.T) {
+	h := newHarness(t, DefaultConfig())
+	defer h.stop()
+	cats := h.pingdom.Add(newCheckResponse("[default/default/pets] cats.example.com", "cats.example.com"))
+	h.addIngress(newIngressWithChecks("default", "pets", "pets", map[string]int{"cats.example.com": cats}))
+	h.o.rebuildChecks()
+
+	// The Check spec is applied to the rebuilt checks before the Ingress
+	// is reconciled.
+	h.createCheck(newCheck("default", "pets", tpr.Spec{Resolution: 15}))
+	for h.o.queue.Len() > 0 {
+		h.o.processNextItem()
+	}
+	assert.Equal(t, 15, h.pingdom.Checks()[cats].Resolution)
+
+	h.resync()
+	h.sync()
+	assert.Equal(t, 0, h.pingdom.Calls(fake.OpCreate))
+	assert.Equal(t, []string{"cats.example.com"}, h.pingdom.Hosts())
+	assert.Equal(t, 15, h.pingdom.Checks()[cats].Resolution)
+}
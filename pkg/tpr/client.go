@@ -0,0 +1,169 @@
+package tpr
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/pkg/watch"
+	"k8s.io/client-go/rest"
+)
+
+// Client reads and writes Check resources.
+type Client struct {
+	rest rest.Interface
+
+	kind    string
+	group   string
+	version string
+}
+
+// NewClient creates a client for the Check resources of the configured
+// group and version.
+func NewClient(clientset kubernetes.Interface, config Config) *Client {
+	return &Client{
+		rest:    clientset.CoreV1().RESTClient(),
+		kind:    tprKind,
+		group:   config.Group,
+		version: config.Version,
+	}
+}
+
+// CheckGetter returns the client of the Check resources in a namespace.
+type CheckGetter interface {
+	Checks(namespace string) CheckInterface
+}
+
+// Checks returns the client of the Check resources in the namespace. An
+// empty namespace lists and watches all namespaces.
+func (c *Client) Checks(namespace string) CheckInterface {
+	return &checks{client: c, namespace: namespace}
+}
+
+// CheckInterface has methods to work with Check resources.
+type CheckInterface interface {
+	Create(*PingdomCheck) (*PingdomCheck, error)
+	Update(*PingdomCheck) (*PingdomCheck, error)
+	UpdateStatus(*PingdomCheck) (*PingdomCheck, error)
+	Delete(name string) error
+	Get(name string) (*PingdomCheck, error)
+	List(opts v1.ListOptions) (*PingdomCheckList, error)
+	Watch(opts v1.ListOptions) (watch.Interface, error)
+}
+
+type checks struct {
+	client    *Client
+	namespace string
+}
+
+func (c *checks) path(name string) string {
+	path := fmt.Sprintf("/apis/%s/%s", c.client.group, c.client.version)
+	if c.namespace != "" {
+		path += "/namespaces/" + c.namespace
+	}
+	path += fmt.Sprintf("/%ss", c.client.kind)
+	if name != "" {
+		path += "/" + name
+	}
+	return path
+}
+
+// Create creates the Check.
+func (c *checks) Create(check *PingdomCheck) (*PingdomCheck, error) {
+	return c.send(c.client.rest.Post().AbsPath(c.path("")), check)
+}
+
+// Update replaces the Check. Changes of the status are ignored.
+func (c *checks) Update(check *PingdomCheck) (*PingdomCheck, error) {
+	return c.send(c.client.rest.Put().AbsPath(c.path(check.Name)), check)
+}
+
+// UpdateStatus replaces the status of the Check.
+func (c *checks) UpdateStatus(check *PingdomCheck) (*PingdomCheck, error) {
+	return c.send(c.client.rest.Put().AbsPath(c.path(check.Name), "status"), check)
+}
+
+// Delete deletes the Check.
+func (c *checks) Delete(name string) error {
+	return c.client.rest.Delete().AbsPath(c.path(name)).Do().Error()
+}
+
+// Get returns the Check.
+func (c *checks) Get(name string) (*PingdomCheck, error) {
+	data, err := c.client.rest.Get().AbsPath(c.path(name)).DoRaw()
+	if err != nil {
+		return nil, err
+	}
+	check := new(PingdomCheck)
+	if err := json.Unmarshal(data, check); err != nil {
+		return nil, err
+	}
+	return check, nil
+}
+
+// List returns the Checks matching the options.
+func (c *checks) List(opts v1.ListOptions) (*PingdomCheckList, error) {
+	req := listParams(c.client.rest.Get().AbsPath(c.path("")), opts)
+	data, err := req.DoRaw()
+	if err != nil {
+		return nil, err
+	}
+	list := new(PingdomCheckList)
+	if err := json.Unmarshal(data, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Watch streams the changes of the Checks matching the options, starting
+// after opts.ResourceVersion.
+func (c *checks) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	opts.Watch = true
+	req := listParams(c.client.rest.Get().AbsPath(c.path("")), opts)
+	stream, err := req.Stream()
+	if err != nil {
+		return nil, err
+	}
+	return watch.NewStreamWatcher(newDecoder(stream, pingdomCheckFuncs{})), nil
+}
+
+func (c *checks) send(req *rest.Request, check *PingdomCheck) (*PingdomCheck, error) {
+	body, err := json.Marshal(check)
+	if err != nil {
+		return nil, err
+	}
+	data, err := req.
+		SetHeader("Content-Type", "application/json").
+		Body(body).
+		DoRaw()
+	if err != nil {
+		return nil, err
+	}
+	result := new(PingdomCheck)
+	if err := json.Unmarshal(data, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// listParams sets the query parameters of list and watch requests.
+func listParams(req *rest.Request, opts v1.ListOptions) *rest.Request {
+	if opts.LabelSelector != "" {
+		req = req.Param("labelSelector", opts.LabelSelector)
+	}
+	if opts.FieldSelector != "" {
+		req = req.Param("fieldSelector", opts.FieldSelector)
+	}
+	if opts.ResourceVersion != "" {
+		req = req.Param("resourceVersion", opts.ResourceVersion)
+	}
+	if opts.TimeoutSeconds != nil {
+		req = req.Param("timeoutSeconds", strconv.FormatInt(*opts.TimeoutSeconds, 10))
+	}
+	if opts.Watch {
+		req = req.Param("watch", "true")
+	}
+	return req
+}
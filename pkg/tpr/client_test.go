@@ -0,0 +1,72 @@
+package tpr
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/pkg/api/errors"
+	"k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/pkg/labels"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestListOptions(t *testing.T) {
+	var path string
+	var query url.Values
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, query = r.URL.Path, r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"metadata": {"resourceVersion": "42"}, "items": [{"metadata": {"name": "pets"}}]}`))
+	}))
+	defer s.Close()
+
+	clientset, err := kubernetes.NewForConfig(&rest.Config{Host: s.URL})
+	assert.Nil(t, err)
+
+	timeout := int64(30)
+	list, err := NewClient(clientset, DefaultConfig()).Checks("default").List(v1.ListOptions{
+		LabelSelector:   "app=pets",
+		ResourceVersion: "10",
+		TimeoutSeconds:  &timeout,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "42", list.ResourceVersion)
+	assert.Equal(t, 1, len(list.Items))
+
+	assert.Equal(t, "/apis/pingdom.example.com/v1alpha1/namespaces/default/checks", path)
+	assert.Equal(t, "app=pets", query.Get("labelSelector"))
+	assert.Equal(t, "10", query.Get("resourceVersion"))
+	assert.Equal(t, "30", query.Get("timeoutSeconds"))
+	assert.Equal(t, "", query.Get("watch"))
+}
+
+func TestCheckLister(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, key := range [][2]string{{"ns1", "a"}, {"ns1", "b"}, {"ns2", "a"}} {
+		check := &PingdomCheck{}
+		check.Namespace, check.Name = key[0], key[1]
+		indexer.Add(check)
+	}
+	lister := NewCheckLister(indexer)
+
+	all, err := lister.List(labels.Everything())
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(all))
+
+	ns1, err := lister.Checks("ns1").List(labels.Everything())
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(ns1))
+
+	check, err := lister.Checks("ns2").Get("a")
+	assert.Nil(t, err)
+	assert.Equal(t, "ns2", check.Namespace)
+
+	_, err = lister.Checks("ns2").Get("b")
+	assert.True(t, errors.IsNotFound(err))
+}
@@ -0,0 +1,226 @@
+package tpr
+
+import (
+	"k8s.io/client-go/pkg/api/unversioned"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+// client-go does not ship the apiextensions types, so the subset of the
+// apiextensions.k8s.io/v1 CustomResourceDefinition used here is declared
+// below and sent to the API server as JSON.
+
+const (
+	crdAPIVersion = "apiextensions.k8s.io/v1"
+	crdKind       = "CustomResourceDefinition"
+	crdEndpoint   = "/apis/apiextensions.k8s.io/v1/customresourcedefinitions"
+)
+
+type customResourceDefinition struct {
+	unversioned.TypeMeta `json:",inline"`
+	v1.ObjectMeta        `json:"metadata,omitempty"`
+
+	Spec crdSpec `json:"spec"`
+}
+
+type crdSpec struct {
+	Group    string       `json:"group"`
+	Names    crdNames     `json:"names"`
+	Scope    string       `json:"scope"`
+	Versions []crdVersion `json:"versions"`
+}
+
+type crdNames struct {
+	Plural   string `json:"plural"`
+	Singular string `json:"singular"`
+	Kind     string `json:"kind"`
+	ListKind string `json:"listKind"`
+}
+
+type crdVersion struct {
+	Name                     string             `json:"name"`
+	Served                   bool               `json:"served"`
+	Storage                  bool               `json:"storage"`
+	Schema                   crdValidation      `json:"schema"`
+	Subresources             *crdSubresources   `json:"subresources,omitempty"`
+	AdditionalPrinterColumns []crdPrinterColumn `json:"additionalPrinterColumns,omitempty"`
+}
+
+type crdValidation struct {
+	OpenAPIV3Schema *jsonSchema `json:"openAPIV3Schema"`
+}
+
+type crdSubresources struct {
+	Status *struct{} `json:"status,omitempty"`
+}
+
+type crdPrinterColumn struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	JSONPath    string `json:"jsonPath"`
+	Description string `json:"description,omitempty"`
+}
+
+type jsonSchema struct {
+	Type                 string                `json:"type,omitempty"`
+	Format               string                `json:"format,omitempty"`
+	Nullable             bool                  `json:"nullable,omitempty"`
+	Description          string                `json:"description,omitempty"`
+	Minimum              *float64              `json:"minimum,omitempty"`
+	Enum                 []interface{}         `json:"enum,omitempty"`
+	Required             []string              `json:"required,omitempty"`
+	Properties           map[string]jsonSchema `json:"properties,omitempty"`
+	Items                *jsonSchema           `json:"items,omitempty"`
+	AdditionalProperties *jsonSchema           `json:"additionalProperties,omitempty"`
+}
+
+// newCRD returns the definition of the Check resource of the given group
+// and version.
+func newCRD(kind, group, version, description string) *customResourceDefinition {
+	plural := kind + "s"
+	crd := &customResourceDefinition{
+		Spec: crdSpec{
+			Group: group,
+			Names: crdNames{
+				Plural:   plural,
+				Singular: kind,
+				Kind:     checkKind,
+				ListKind: checkKind + "List",
+			},
+			Scope: "Namespaced",
+			Versions: []crdVersion{{
+				Name:    version,
+				Served:  true,
+				Storage: true,
+				Schema: crdValidation{
+					OpenAPIV3Schema: checkSchema(description),
+				},
+				Subresources: &crdSubresources{Status: &struct{}{}},
+				AdditionalPrinterColumns: []crdPrinterColumn{
+					{Name: "Type", Type: "string", JSONPath: ".spec.type"},
+					{Name: "Resolution", Type: "integer", JSONPath: ".spec.resolution"},
+					{Name: "Ready", Type: "string", JSONPath: `.status.conditions[?(@.type=="Ready")].status`},
+					{Name: "Age", Type: "date", JSONPath: ".metadata.creationTimestamp"},
+				},
+			}},
+		},
+	}
+	crd.APIVersion = crdAPIVersion
+	crd.Kind = crdKind
+	crd.Name = plural + "." + group
+	return crd
+}
+
+// checkSchema returns the structural schema of PingdomCheck.
+func checkSchema(description string) *jsonSchema {
+	str := jsonSchema{Type: "string"}
+	integer := jsonSchema{Type: "integer"}
+	boolean := jsonSchema{Type: "boolean"}
+	minimum := func(min float64, desc string) jsonSchema {
+		return jsonSchema{Type: "integer", Minimum: &min, Description: desc}
+	}
+	describe := func(s jsonSchema, desc string) jsonSchema {
+		s.Description = desc
+		return s
+	}
+
+	spec := jsonSchema{
+		Type:     "object",
+		Required: []string{"resolution"},
+		Properties: map[string]jsonSchema{
+			"type": describe(str, "Type of the check: http, tcp, dns or ping. Defaults to http."),
+			"resolution": {
+				Type:        "integer",
+				Enum:        []interface{}{1, 5, 15, 30, 60},
+				Description: "Interval in minutes: 1, 5, 15, 30 or 60.",
+			},
+			"url":              describe(str, "Path of the checked URL."),
+			"encryption":       describe(boolean, "Use HTTPS."),
+			"port":             minimum(1, "Target port of http and tcp checks."),
+			"shouldContain":    describe(str, "The response must contain this string."),
+			"shouldNotContain": describe(str, "The response must not contain this string."),
+			"requestHeaders": {
+				Type:                 "object",
+				Description:          "Custom request headers.",
+				AdditionalProperties: &str,
+			},
+			"basicAuth": {
+				Type:        "object",
+				Description: "Basic auth credentials read from a Secret.",
+				Required:    []string{"secretName"},
+				Properties: map[string]jsonSchema{
+					"secretName":  str,
+					"usernameKey": str,
+					"passwordKey": str,
+				},
+			},
+			"paused":              describe(boolean, "Pause the check."),
+			"notifyAfterFailures": minimum(1, "Send a notification when down this many consecutive tests."),
+			"notifyAgainEvery":    minimum(0, "Notify again every n tests while down, 0 means never."),
+			"contactIds":          {Type: "array", Items: &integer},
+			"integrationIds":      {Type: "array", Items: &integer},
+			"tcp": {
+				Type: "object",
+				Properties: map[string]jsonSchema{
+					"stringToSend":   str,
+					"stringToExpect": str,
+				},
+			},
+			"dns": {
+				Type:     "object",
+				Required: []string{"nameServer", "expectedIP"},
+				Properties: map[string]jsonSchema{
+					"nameServer": str,
+					"expectedIP": str,
+				},
+			},
+		},
+	}
+
+	// unversioned.Time encodes the zero time as null.
+	dateTime := jsonSchema{Type: "string", Format: "date-time", Nullable: true}
+	status := jsonSchema{
+		Type: "object",
+		Properties: map[string]jsonSchema{
+			"ingresses": {
+				Type: "array",
+				Items: &jsonSchema{
+					Type:     "object",
+					Required: []string{"name"},
+					Properties: map[string]jsonSchema{
+						"name": str,
+						"checks": {
+							Type:                 "object",
+							AdditionalProperties: &integer,
+						},
+					},
+				},
+			},
+			"lastSyncTime":       dateTime,
+			"observedGeneration": {Type: "integer", Format: "int64"},
+			"conditions": {
+				Type: "array",
+				Items: &jsonSchema{
+					Type:     "object",
+					Required: []string{"type", "status"},
+					Properties: map[string]jsonSchema{
+						"type":               str,
+						"status":             str,
+						"lastTransitionTime": dateTime,
+						"reason":             str,
+						"message":            str,
+					},
+				},
+			},
+		},
+	}
+
+	return &jsonSchema{
+		Type:        "object",
+		Description: description,
+		Required:    []string{"spec"},
+		Properties: map[string]jsonSchema{
+			"spec":   spec,
+			"status": status,
+		},
+	}
+}
@@ -0,0 +1,71 @@
+package tpr
+
+import (
+	"encoding/json"
+	"io"
+
+	"k8s.io/client-go/pkg/api/unversioned"
+	"k8s.io/client-go/pkg/runtime"
+	"k8s.io/client-go/pkg/watch"
+)
+
+// zeroObjectFuncs provides zero values of an object and objects' list ready to
+// be decoded. The provided zero values must not be reused by zeroObjectFuncs.
+type zeroObjectFuncs interface {
+	NewObject() runtime.Object
+	NewObjectList() runtime.Object
+}
+
+// decoder decodes the events of a watch stream. A single json.Decoder is
+// used for the whole stream, as it may buffer bytes of following events.
+type decoder struct {
+	stream io.ReadCloser
+	dec    *json.Decoder
+	obj    zeroObjectFuncs
+}
+
+func newDecoder(stream io.ReadCloser, obj zeroObjectFuncs) *decoder {
+	return &decoder{
+		stream: stream,
+		dec:    json.NewDecoder(stream),
+		obj:    obj,
+	}
+}
+
+// Decode returns the next event of the stream. ERROR events carry the
+// unversioned.Status sent by the API server, which makes the reflector
+// relist, e.g. when the resource version is too old. Events whose object
+// can't be decoded are skipped.
+func (d *decoder) Decode() (action watch.EventType, object runtime.Object, err error) {
+	for {
+		var e struct {
+			Type   watch.EventType
+			Object json.RawMessage
+		}
+		if err := d.dec.Decode(&e); err != nil {
+			return watch.Error, nil, err
+		}
+
+		if e.Type == watch.Error {
+			status := new(unversioned.Status)
+			if err := json.Unmarshal(e.Object, status); err != nil {
+				status = &unversioned.Status{
+					Status:  unversioned.StatusFailure,
+					Message: string(e.Object),
+				}
+			}
+			return e.Type, status, nil
+		}
+
+		obj := d.obj.NewObject()
+		if err := json.Unmarshal(e.Object, obj); err != nil {
+			logger.WithError(err).WithField("type", e.Type).Warningf("Skipping watch event: %s", e.Object)
+			continue
+		}
+		return e.Type, obj, nil
+	}
+}
+
+func (d *decoder) Close() {
+	d.stream.Close()
+}
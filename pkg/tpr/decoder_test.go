@@ -0,0 +1,115 @@
+package tpr
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/client-go/pkg/api/errors"
+	"k8s.io/client-go/pkg/api/unversioned"
+	"k8s.io/client-go/pkg/watch"
+)
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// Replays a recorded watch stream, either at once or split into small reads
+// like events arriving in several TCP chunks.
+func replay(t *testing.T, name string, wrap func(io.Reader) io.Reader) *decoder {
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newDecoder(readCloser{Reader: wrap(f), Closer: f}, pingdomCheckFuncs{})
+}
+
+var readers = map[string]func(io.Reader) io.Reader{
+	"whole":   func(r io.Reader) io.Reader { return r },
+	"onebyte": iotest.OneByteReader,
+	"half":    iotest.HalfReader,
+}
+
+func TestDecodeEvents(t *testing.T) {
+	for name, wrap := range readers {
+		d := replay(t, "watch-events.json", wrap)
+
+		var events []string
+		var last *PingdomCheck
+		for {
+			action, obj, err := d.Decode()
+			if err == io.EOF {
+				break
+			}
+			if !assert.Nil(t, err, name) {
+				break
+			}
+			last = obj.(*PingdomCheck)
+			events = append(events, string(action)+" "+last.Name+" "+last.ResourceVersion)
+		}
+		d.Close()
+
+		assert.Equal(t, []string{
+			"ADDED pets 101",
+			"MODIFIED pets 102",
+			"ADDED ants 103",
+			"DELETED pets 104",
+		}, events, name)
+		assert.Equal(t, 5, last.Spec.Resolution, name)
+	}
+}
+
+func TestDecodeErrorEvent(t *testing.T) {
+	d := replay(t, "watch-gone.json", iotest.OneByteReader)
+	defer d.Close()
+
+	action, _, err := d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, watch.Added, action)
+
+	action, obj, err := d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, watch.Error, action)
+	status, ok := obj.(*unversioned.Status)
+	if assert.True(t, ok) {
+		assert.Equal(t, int32(410), status.Code)
+		assert.Equal(t, unversioned.StatusReasonGone, status.Reason)
+	}
+	// The reflector turns the event into an error and relists.
+	err = errors.FromObject(obj)
+	if assert.IsType(t, &errors.StatusError{}, err) {
+		assert.Equal(t, unversioned.StatusReasonGone, err.(*errors.StatusError).ErrStatus.Reason)
+	}
+
+	_, _, err = d.Decode()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestDecodeSkipsBadObject(t *testing.T) {
+	d := replay(t, "watch-bad-object.json", iotest.HalfReader)
+	defer d.Close()
+
+	action, obj, err := d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, watch.Added, action)
+	assert.Equal(t, "ants", obj.(*PingdomCheck).Name)
+
+	_, _, err = d.Decode()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestStreamWatcher(t *testing.T) {
+	d := replay(t, "watch-bad-object.json", func(r io.Reader) io.Reader { return r })
+	w := watch.NewStreamWatcher(d)
+
+	var names []string
+	for e := range w.ResultChan() {
+		names = append(names, e.Object.(*PingdomCheck).Name)
+	}
+	assert.Equal(t, []string{"ants"}, names)
+}
@@ -0,0 +1,83 @@
+package tpr
+
+import (
+	"k8s.io/client-go/pkg/api"
+	"k8s.io/client-go/pkg/api/errors"
+	"k8s.io/client-go/pkg/api/unversioned"
+	"k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/pkg/labels"
+	"k8s.io/client-go/pkg/runtime"
+	"k8s.io/client-go/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// NewCheckInformer returns a shared informer of the Check resources in the
+// namespace, or all namespaces if empty, indexed by namespace.
+func NewCheckInformer(client CheckGetter, namespace string) cache.SharedIndexInformer {
+	checks := client.Checks(namespace)
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options api.ListOptions) (runtime.Object, error) {
+				var v1Options v1.ListOptions
+				v1.Convert_api_ListOptions_To_v1_ListOptions(&options, &v1Options, nil)
+				return checks.List(v1Options)
+			},
+			WatchFunc: func(options api.ListOptions) (watch.Interface, error) {
+				var v1Options v1.ListOptions
+				v1.Convert_api_ListOptions_To_v1_ListOptions(&options, &v1Options, nil)
+				return checks.Watch(v1Options)
+			},
+		},
+		&PingdomCheck{}, 0,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+	)
+}
+
+// CheckLister lists Check resources from the informer cache.
+type CheckLister struct {
+	indexer cache.Indexer
+}
+
+// NewCheckLister returns a lister reading the indexer of a Check informer.
+func NewCheckLister(indexer cache.Indexer) CheckLister {
+	return CheckLister{indexer: indexer}
+}
+
+// List returns the Checks of all namespaces matching the selector.
+func (l CheckLister) List(selector labels.Selector) (ret []*PingdomCheck, err error) {
+	err = cache.ListAll(l.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*PingdomCheck))
+	})
+	return ret, err
+}
+
+// Checks returns a lister of the Checks in the namespace.
+func (l CheckLister) Checks(namespace string) CheckNamespaceLister {
+	return CheckNamespaceLister{indexer: l.indexer, namespace: namespace}
+}
+
+// CheckNamespaceLister lists Check resources of a namespace.
+type CheckNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List returns the Checks of the namespace matching the selector.
+func (l CheckNamespaceLister) List(selector labels.Selector) (ret []*PingdomCheck, err error) {
+	err = cache.ListAllByNamespace(l.indexer, l.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*PingdomCheck))
+	})
+	return ret, err
+}
+
+// Get returns the Check or a NotFound error.
+func (l CheckNamespaceLister) Get(name string) (*PingdomCheck, error) {
+	obj, exists, err := l.indexer.GetByKey(l.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(unversioned.GroupResource{Group: DefaultGroup, Resource: tprKind + "s"}, name)
+	}
+	return obj.(*PingdomCheck), nil
+}
@@ -1,90 +1,107 @@
 package tpr
 
 import (
+	"fmt"
 	"sync/atomic"
 	"time"
 
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/cache"
+	"github.com/sirupsen/logrus"
 
-	logging "github.com/op/go-logging"
+	"k8s.io/client-go/kubernetes"
 )
 
 const (
 	initRetryDelay = 10 * time.Second
 
 	tprKind        = "check"
-	tprGroup       = "pingdom.example.com"
-	tprVersion     = "v1alpha1"
+	checkKind      = "Check"
 	tprDescription = "Managed Pingdom uptime checks for Ingress hosts"
+
+	// Default API group and version of the Check resource.
+	DefaultGroup   = "pingdom.example.com"
+	DefaultVersion = "v1alpha1"
 )
 
 var (
-	logger = logging.MustGetLogger("pingdom")
+	logger = logrus.WithField("component", "tpr")
 )
 
+// Config of the Check resource.
+type Config struct {
+	// API group and version of the Check resource.
+	Group   string
+	Version string
+	// CreateCRD registers the CRD. It needs cluster-wide permissions,
+	// operators with namespaced Roles only rely on the CRD being installed
+	// by an admin.
+	CreateCRD bool
+}
+
+// DefaultConfig returns the config with the default settings.
+func DefaultConfig() Config {
+	return Config{
+		Group:     DefaultGroup,
+		Version:   DefaultVersion,
+		CreateCRD: true,
+	}
+}
+
 type Operator struct {
 	tpr       *tpr
-	namespace string
-	clientset kubernetes.Interface
-	store     *Store
-	eventCnt  uint64
+	createCRD bool
+
+	// Set to 1 once the CRD is registered.
+	registered int32
 }
 
-func New(namespace string, clientset kubernetes.Interface, store *Store) *Operator {
-	return &Operator{
-		tpr:       newTPR(clientset, tprKind, tprGroup, tprVersion, tprDescription, namespace),
-		namespace: namespace,
-		clientset: clientset,
-		store:     store,
-		eventCnt:  0,
+// New creates the operator registering the Check resource. The Checks are
+// watched by the informers of their consumers.
+func New(config Config, clientset kubernetes.Interface) *Operator {
+	o := &Operator{
+		tpr:       newTPR(clientset, tprKind, config.Group, config.Version, tprDescription),
+		createCRD: config.CreateCRD,
+	}
+	if !config.CreateCRD {
+		o.registered = 1
 	}
+	return o
 }
 
+// Run registers the CRD, retrying until it succeeds, and blocks until
+// stopCh is closed.
 func (o *Operator) Run(stopCh <-chan struct{}) error {
-	for {
+	for o.createCRD {
 		err := o.initResources()
 		if err == nil {
 			break
 		}
-		logger.Errorf("Failed to init resources: %+v. retrying...", err)
-		<-time.After(initRetryDelay)
+		logger.WithError(err).Error("Failed to init resources, retrying")
+		select {
+		case <-time.After(initRetryDelay):
+		case <-stopCh:
+			return nil
+		}
 	}
 
-	watcher := o.tpr.Watcher(pingdomCheckFuncs{}, cache.ResourceEventHandlerFuncs{
-		AddFunc: func(obj interface{}) {
-			check := obj.(*PingdomCheck)
-			id := atomic.AddUint64(&o.eventCnt, 1)
-			logger.Debugf("AddPingdomCheck[%d] obj=%s", id, check.Name)
-			defer logger.Debugf("AddPingdomCheck[%d] end", id)
-			o.store.set(check)
-		},
-		UpdateFunc: func(oldObj, newObj interface{}) {
-			old, new := oldObj.(*PingdomCheck), newObj.(*PingdomCheck)
-			id := atomic.AddUint64(&o.eventCnt, 1)
-			logger.Debugf("UpdatePingdomCheck[%d] old=%s new=%s", id,
-				old.Name, new.Name)
-			defer logger.Debugf("UpdatePingdomCheck[%d] end", id)
-			o.store.set(new)
-		},
-		DeleteFunc: func(obj interface{}) {
-			check := obj.(*PingdomCheck)
-			id := atomic.AddUint64(&o.eventCnt, 1)
-			logger.Debugf("DeletePingdomCheck[%d] obj=%s", id, check.Name)
-			defer logger.Debugf("DeletePingdomCheck[%d] end", id)
-			o.store.delete(check)
-		},
-	})
-
-	watcher.Run(stopCh)
+	<-stopCh
+	return nil
+}
+
+// Ready returns an error until the CRD is registered.
+func (o *Operator) Ready() error {
+	if atomic.LoadInt32(&o.registered) == 0 {
+		return fmt.Errorf("CRD %s not registered", o.tpr.Name())
+	}
 	return nil
 }
 
 func (o *Operator) initResources() error {
-	logger.Infof("creating TPR: %s", o.tpr.Name())
+	crdLogger := logger.WithField("crd", o.tpr.Name())
+	crdLogger.Info("Creating CRD")
 	err := o.tpr.CreateAndWait()
 	if err == nil {
-		logger.Infof("creating TPR: success")
+		crdLogger.Info("Created CRD")
+		atomic.StoreInt32(&o.registered, 1)
 	}
 	return err
 }
@@ -1,14 +1,153 @@
 package tpr
 
 import (
+	"fmt"
+	"strings"
+
 	"k8s.io/client-go/pkg/api/unversioned"
 	"k8s.io/client-go/pkg/api/v1"
 	"k8s.io/client-go/pkg/runtime"
 )
 
+// Pingdom check types.
+const (
+	CheckTypeHTTP = "http"
+	CheckTypeTCP  = "tcp"
+	CheckTypeDNS  = "dns"
+	CheckTypePing = "ping"
+)
+
 type Spec struct {
+	// Type of the check, defaults to http.
+	Type string `json:"type,omitempty"`
 	// Interval in minutes.
 	Resolution int `json:"resolution"`
+
+	// Path of the checked URL, e.g. "/healthz".
+	URL string `json:"url,omitempty"`
+	// Use HTTPS.
+	Encryption bool `json:"encryption,omitempty"`
+	// Target port of http and tcp checks. Http checks default to 80 or
+	// 443 with encryption.
+	Port int `json:"port,omitempty"`
+	// The response must contain this string.
+	ShouldContain string `json:"shouldContain,omitempty"`
+	// The response must not contain this string.
+	ShouldNotContain string `json:"shouldNotContain,omitempty"`
+	// Custom request headers.
+	RequestHeaders map[string]string `json:"requestHeaders,omitempty"`
+	// Basic auth credentials read from a Secret.
+	BasicAuth *BasicAuth `json:"basicAuth,omitempty"`
+
+	// Pause the check.
+	Paused bool `json:"paused,omitempty"`
+	// Send a notification when down this many consecutive tests.
+	NotifyAfterFailures int `json:"notifyAfterFailures,omitempty"`
+	// Notify again every n tests while down, 0 means never.
+	NotifyAgainEvery int `json:"notifyAgainEvery,omitempty"`
+	// Pingdom contacts and integrations to notify.
+	ContactIDs     []int `json:"contactIds,omitempty"`
+	IntegrationIDs []int `json:"integrationIds,omitempty"`
+
+	TCP *TCPSpec `json:"tcp,omitempty"`
+	DNS *DNSSpec `json:"dns,omitempty"`
+}
+
+// TCPSpec holds the fields of tcp checks.
+type TCPSpec struct {
+	// String sent once connected.
+	StringToSend string `json:"stringToSend,omitempty"`
+	// String expected in the response.
+	StringToExpect string `json:"stringToExpect,omitempty"`
+}
+
+// DNSSpec holds the fields of dns checks.
+type DNSSpec struct {
+	// Name server queried for the host.
+	NameServer string `json:"nameServer"`
+	// IP address the host is expected to resolve to.
+	ExpectedIP string `json:"expectedIP"`
+}
+
+// CheckType returns the type of the check in lower case.
+func (s Spec) CheckType() string {
+	if s.Type == "" {
+		return CheckTypeHTTP
+	}
+	return strings.ToLower(s.Type)
+}
+
+// Validate checks the fields required by the check type are set.
+func (s Spec) Validate() error {
+	switch s.CheckType() {
+	case CheckTypeHTTP, CheckTypePing:
+		return nil
+	case CheckTypeTCP:
+		if s.Port <= 0 {
+			return fmt.Errorf("tcp check requires port")
+		}
+		return nil
+	case CheckTypeDNS:
+		if s.DNS == nil || s.DNS.NameServer == "" || s.DNS.ExpectedIP == "" {
+			return fmt.Errorf("dns check requires dns.nameServer and dns.expectedIP")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown check type %q", s.Type)
+	}
+}
+
+// BasicAuth references a Secret in the namespace of the Check.
+type BasicAuth struct {
+	SecretName string `json:"secretName"`
+	// Keys of the Secret, default to "username" and "password".
+	UsernameKey string `json:"usernameKey,omitempty"`
+	PasswordKey string `json:"passwordKey,omitempty"`
+}
+
+// Status of the Pingdom checks using the spec.
+type Status struct {
+	// Ingresses referencing the Check and their Pingdom checks.
+	Ingresses []IngressStatus `json:"ingresses,omitempty"`
+	// Last time the spec was propagated to Pingdom.
+	LastSyncTime unversioned.Time `json:"lastSyncTime,omitempty"`
+	// Generation of the spec last propagated to Pingdom.
+	ObservedGeneration int64       `json:"observedGeneration,omitempty"`
+	Conditions         []Condition `json:"conditions,omitempty"`
+}
+
+// IngressStatus lists the Pingdom checks of an Ingress.
+type IngressStatus struct {
+	Name string `json:"name"`
+	// Pingdom check IDs by host.
+	Checks map[string]int `json:"checks,omitempty"`
+}
+
+// ConditionReady is true if the spec was propagated to all Pingdom checks.
+const ConditionReady = "Ready"
+
+type Condition struct {
+	Type               string             `json:"type"`
+	Status             v1.ConditionStatus `json:"status"`
+	LastTransitionTime unversioned.Time   `json:"lastTransitionTime,omitempty"`
+	Reason             string             `json:"reason,omitempty"`
+	Message            string             `json:"message,omitempty"`
+}
+
+// SetCondition adds or replaces the condition of the same type. The
+// transition time is kept if the condition status did not change.
+func (s *Status) SetCondition(c Condition) {
+	for i, old := range s.Conditions {
+		if old.Type != c.Type {
+			continue
+		}
+		if old.Status == c.Status {
+			c.LastTransitionTime = old.LastTransitionTime
+		}
+		s.Conditions[i] = c
+		return
+	}
+	s.Conditions = append(s.Conditions, c)
 }
 
 /*
@@ -24,7 +163,8 @@ type PingdomCheck struct {
 	unversioned.TypeMeta `json:",inline"`
 	v1.ObjectMeta        `json:"metadata,omitempty"`
 
-	Spec Spec `json:"spec"`
+	Spec   Spec   `json:"spec"`
+	Status Status `json:"status,omitempty"`
 }
 
 type PingdomCheckList struct {
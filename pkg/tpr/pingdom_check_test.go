@@ -3,8 +3,10 @@ package tpr
 import (
 	"encoding/json"
 	"testing"
+	"time"
 
 	"k8s.io/client-go/pkg/api/unversioned"
+	"k8s.io/client-go/pkg/api/v1"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -26,3 +28,69 @@ func TestPingdomCheckUnmarshal(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, want, v)
 }
+
+var checkData = `{
+	"kind":"Check","apiVersion":"pingdom.example.com/v1alpha1",
+	"metadata":{"name":"pets","namespace":"default"},
+	"spec":{
+		"resolution":5,
+		"url":"/healthz",
+		"encryption":true,
+		"requestHeaders":{"X-Check":"pingdom"},
+		"basicAuth":{"secretName":"pets-auth"},
+		"notifyAfterFailures":3,
+		"contactIds":[1,2]
+	}
+}`
+
+func TestPingdomCheckSpecUnmarshal(t *testing.T) {
+	want := Spec{
+		Resolution:          5,
+		URL:                 "/healthz",
+		Encryption:          true,
+		RequestHeaders:      map[string]string{"X-Check": "pingdom"},
+		BasicAuth:           &BasicAuth{SecretName: "pets-auth"},
+		NotifyAfterFailures: 3,
+		ContactIDs:          []int{1, 2},
+	}
+	v := new(PingdomCheck)
+	err := json.Unmarshal([]byte(checkData), v)
+	assert.Nil(t, err)
+	assert.Equal(t, want, v.Spec)
+}
+
+func TestSpecValidate(t *testing.T) {
+	valid := []Spec{
+		{},
+		{Type: "HTTP"},
+		{Type: CheckTypePing},
+		{Type: CheckTypeTCP, Port: 22},
+		{Type: "DNS", DNS: &DNSSpec{NameServer: "8.8.8.8", ExpectedIP: "10.0.0.1"}},
+	}
+	for _, s := range valid {
+		assert.Nil(t, s.Validate(), "%+v", s)
+	}
+
+	invalid := []Spec{
+		{Type: "smtp"},
+		{Type: CheckTypeTCP},
+		{Type: CheckTypeDNS},
+		{Type: CheckTypeDNS, DNS: &DNSSpec{NameServer: "8.8.8.8"}},
+	}
+	for _, s := range invalid {
+		assert.NotNil(t, s.Validate(), "%+v", s)
+	}
+}
+
+func TestStatusSetCondition(t *testing.T) {
+	t1 := unversioned.NewTime(time.Unix(1, 0))
+	t2 := unversioned.NewTime(time.Unix(2, 0))
+	var s Status
+
+	s.SetCondition(Condition{Type: ConditionReady, Status: v1.ConditionTrue, LastTransitionTime: t1})
+	s.SetCondition(Condition{Type: ConditionReady, Status: v1.ConditionTrue, LastTransitionTime: t2, Reason: "Synced"})
+	assert.Equal(t, []Condition{{Type: ConditionReady, Status: v1.ConditionTrue, LastTransitionTime: t1, Reason: "Synced"}}, s.Conditions)
+
+	s.SetCondition(Condition{Type: ConditionReady, Status: v1.ConditionFalse, LastTransitionTime: t2})
+	assert.Equal(t, []Condition{{Type: ConditionReady, Status: v1.ConditionFalse, LastTransitionTime: t2}}, s.Conditions)
+}
@@ -1,41 +1,132 @@
 package tpr
 
 import (
-	"fmt"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
-	"k8s.io/client-go/kubernetes/fake"
-	"k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 )
 
-func newClientset(nodes int) *fake.Clientset {
-	clientset := fake.NewSimpleClientset()
-	for i := 0; i < nodes; i++ {
-		n := &v1.Node{}
-		n.Name = fmt.Sprintf("node%d", i)
-		clientset.CoreV1().Nodes().Create(n)
+// apiServer records the requests it receives and responds with the
+// registered handlers, or 404.
+type apiServer struct {
+	*httptest.Server
+
+	mux      sync.Mutex
+	requests []string
+	bodies   map[string][]byte
+	handlers map[string]string
+}
+
+func newAPIServer(handlers map[string]string) *apiServer {
+	s := &apiServer{
+		bodies:   make(map[string][]byte),
+		handlers: handlers,
 	}
-	return clientset
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := r.Method + " " + r.URL.Path
+		body, _ := ioutil.ReadAll(r.Body)
+
+		s.mux.Lock()
+		s.requests = append(s.requests, req)
+		s.bodies[req] = body
+		resp, ok := s.handlers[req]
+		s.mux.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`))
+			return
+		}
+		w.Write([]byte(resp))
+	}))
+	return s
 }
 
-func TestCreateTPR(t *testing.T) {
-	clientset := newClientset(3)
+// setHandlers replaces the handlers and clears the recorded requests.
+func (s *apiServer) setHandlers(handlers map[string]string) {
+	s.mux.Lock()
+	s.handlers = handlers
+	s.requests = nil
+	s.mux.Unlock()
+}
 
-	tpr := newTPR(clientset, "testkind", "example.com", "v1test1", "test desc", "default")
+func (s *apiServer) clientset(t *testing.T) kubernetes.Interface {
+	clientset, err := kubernetes.NewForConfig(&rest.Config{Host: s.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return clientset
+}
 
-	resp, err := clientset.ExtensionsV1beta1().ThirdPartyResources().List(v1.ListOptions{})
-	assert.Equal(t, 0, len(resp.Items))
+func TestCreateCRD(t *testing.T) {
+	s := newAPIServer(map[string]string{
+		"POST " + crdEndpoint: `{}`,
+	})
+	defer s.Close()
 
-	err = tpr.create()
+	tpr := newTPR(s.clientset(t), "testkind", "example.com", "v1test1", "test desc")
+	assert.Equal(t, "testkinds.example.com", tpr.Name())
+
+	err := tpr.create()
 	assert.Nil(t, err)
 
-	resp, err = clientset.ExtensionsV1beta1().ThirdPartyResources().List(v1.ListOptions{})
+	var crd customResourceDefinition
+	assert.Nil(t, json.Unmarshal(s.bodies["POST "+crdEndpoint], &crd))
+	assert.Equal(t, crdAPIVersion, crd.APIVersion)
+	assert.Equal(t, "testkinds.example.com", crd.Name)
+	assert.Equal(t, "example.com", crd.Spec.Group)
+	assert.Equal(t, "testkinds", crd.Spec.Names.Plural)
+	assert.Equal(t, 1, len(crd.Spec.Versions))
+
+	v := crd.Spec.Versions[0]
+	assert.Equal(t, "v1test1", v.Name)
+	assert.True(t, v.Served)
+	assert.True(t, v.Storage)
+	assert.NotNil(t, v.Subresources.Status)
+	assert.Equal(t, "test desc", v.Schema.OpenAPIV3Schema.Description)
+	spec := v.Schema.OpenAPIV3Schema.Properties["spec"]
+	assert.Equal(t, "integer", spec.Properties["resolution"].Type)
+	assert.Equal(t, []interface{}{float64(1), float64(5), float64(15), float64(30), float64(60)}, spec.Properties["resolution"].Enum)
+	assert.Equal(t, 1.0, *spec.Properties["notifyAfterFailures"].Minimum)
+	assert.Equal(t, 1.0, *spec.Properties["port"].Minimum)
+	assert.Equal(t, 0.0, *spec.Properties["notifyAgainEvery"].Minimum)
+}
+
+func TestCreateCRDAlreadyExists(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"AlreadyExists","code":409}`))
+	}))
+	defer s.Close()
+
+	clientset, err := kubernetes.NewForConfig(&rest.Config{Host: s.URL})
 	assert.Nil(t, err)
-	assert.Equal(t, 1, len(resp.Items))
-	assert.Equal(t, tpr.Name(), resp.Items[0].Name)
-	assert.Equal(t, 1, len(resp.Items[0].Versions))
-	assert.Equal(t, "v1test1", resp.Items[0].Versions[0].Name)
-	assert.Equal(t, "test desc", resp.Items[0].Description)
+
+	tpr := newTPR(clientset, "testkind", "example.com", "v1test1", "test desc")
+	assert.Nil(t, tpr.create())
+}
+
+func TestCreateAndWait(t *testing.T) {
+	s := newAPIServer(map[string]string{
+		"POST " + crdEndpoint:                     `{}`,
+		"GET /apis/example.com/v1test1/testkinds": `{"items": []}`,
+	})
+	defer s.Close()
+
+	tpr := newTPR(s.clientset(t), "testkind", "example.com", "v1test1", "test desc")
+	assert.Nil(t, tpr.CreateAndWait())
+	assert.Equal(t, []string{
+		"POST " + crdEndpoint,
+		"GET /apis/example.com/v1test1/testkinds",
+	}, s.requests)
 }
@@ -2,6 +2,7 @@ package util
 
 import (
 	"fmt"
+	"math/rand"
 	"time"
 )
 
@@ -29,3 +30,47 @@ func Retry(interval time.Duration, maxRetries int, f ReadyFunc) error {
 	}
 	return fmt.Errorf("util.Retry: not ready after %d retry", maxRetries)
 }
+
+// Backoff is an exponential backoff policy. The delay starts with Base and
+// doubles with every retry up to Max. A random jitter of up to Jitter
+// times the delay is added, so clients failing at the same time don't
+// retry at the same time.
+type Backoff struct {
+	Base    time.Duration
+	Max     time.Duration
+	Jitter  float64
+	Retries int
+}
+
+// Delay returns the delay before the nth retry, starting with 0.
+func (b Backoff) Delay(n int) time.Duration {
+	d := b.Max
+	if n < 32 {
+		if e := b.Base * time.Duration(int64(1)<<uint(n)); e > 0 && e < b.Max {
+			d = e
+		}
+	}
+	if b.Jitter > 0 {
+		d += time.Duration(rand.Float64() * b.Jitter * float64(d))
+	}
+	return d
+}
+
+// RetryBackoff calls f until it is ready, retrying at most b.Retries times
+// with the delays of the backoff. Errors of f are returned as they are and
+// stop retrying.
+func RetryBackoff(b Backoff, f ReadyFunc) error {
+	for i := 0; ; i++ {
+		ok, err := f()
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		if i >= b.Retries {
+			return fmt.Errorf("util.RetryBackoff: not ready after %d retries", b.Retries)
+		}
+		time.Sleep(b.Delay(i))
+	}
+}
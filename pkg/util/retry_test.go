@@ -0,0 +1,50 @@
+package util
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	b := Backoff{Base: time.Second, Max: 10 * time.Second}
+	assert.Equal(t, time.Second, b.Delay(0))
+	assert.Equal(t, 2*time.Second, b.Delay(1))
+	assert.Equal(t, 8*time.Second, b.Delay(3))
+	assert.Equal(t, 10*time.Second, b.Delay(4))
+	assert.Equal(t, 10*time.Second, b.Delay(100))
+
+	b.Jitter = 0.5
+	for i := 0; i < 10; i++ {
+		d := b.Delay(1)
+		assert.True(t, d >= 2*time.Second && d <= 3*time.Second, "%v", d)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	b := Backoff{Base: time.Millisecond, Max: time.Millisecond, Retries: 2}
+
+	var calls int
+	err := RetryBackoff(b, func() (bool, error) {
+		calls++
+		return calls == 2, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, calls)
+
+	calls = 0
+	err = RetryBackoff(b, func() (bool, error) {
+		calls++
+		return false, nil
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, 3, calls)
+
+	fail := errors.New("fail")
+	err = RetryBackoff(b, func() (bool, error) {
+		return false, fail
+	})
+	assert.Equal(t, fail, err)
+}
@@ -0,0 +1,148 @@
+package util
+
+import (
+	"sync"
+	"time"
+)
+
+// WorkQueue is a queue of keys processed by a pool of workers. A key added
+// several times before it is processed is processed once, and a key is
+// never processed by more than one worker at a time. Keys which failed to
+// be processed can be added back with an exponential backoff.
+type WorkQueue struct {
+	cond *sync.Cond
+
+	queue []string
+	// Keys which need processing.
+	dirty map[string]struct{}
+	// Keys being processed by a worker.
+	processing map[string]struct{}
+	// Number of requeues of a key since it was last forgotten.
+	failures map[string]int
+
+	baseDelay time.Duration
+	maxDelay  time.Duration
+
+	shuttingDown bool
+}
+
+// NewWorkQueue creates a queue. The requeue delay of a key starts with
+// baseDelay and doubles with every failure up to maxDelay.
+func NewWorkQueue(baseDelay, maxDelay time.Duration) *WorkQueue {
+	return &WorkQueue{
+		cond:       sync.NewCond(new(sync.Mutex)),
+		dirty:      make(map[string]struct{}),
+		processing: make(map[string]struct{}),
+		failures:   make(map[string]int),
+		baseDelay:  baseDelay,
+		maxDelay:   maxDelay,
+	}
+}
+
+// Add marks key as needing processing.
+func (q *WorkQueue) Add(key string) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+
+	if q.shuttingDown {
+		return
+	}
+	if _, ok := q.dirty[key]; ok {
+		return
+	}
+	q.dirty[key] = struct{}{}
+	// The key is added back to the queue when processing is done.
+	if _, ok := q.processing[key]; ok {
+		return
+	}
+
+	q.queue = append(q.queue, key)
+	q.cond.Signal()
+}
+
+// AddRateLimited adds key after the backoff delay of the key.
+func (q *WorkQueue) AddRateLimited(key string) {
+	q.cond.L.Lock()
+	n := q.failures[key]
+	q.failures[key] = n + 1
+	q.cond.L.Unlock()
+
+	time.AfterFunc(q.delay(n), func() { q.Add(key) })
+}
+
+func (q *WorkQueue) delay(n int) time.Duration {
+	if n > 62 {
+		return q.maxDelay
+	}
+	d := q.baseDelay * time.Duration(int64(1)<<uint(n))
+	if d <= 0 || d > q.maxDelay {
+		return q.maxDelay
+	}
+	return d
+}
+
+// Forget resets the backoff of key. It should be called when key was
+// processed successfully or it won't be retried anymore.
+func (q *WorkQueue) Forget(key string) {
+	q.cond.L.Lock()
+	delete(q.failures, key)
+	q.cond.L.Unlock()
+}
+
+// NumRequeues returns how many times key was added with AddRateLimited
+// since it was last forgotten.
+func (q *WorkQueue) NumRequeues(key string) int {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	return q.failures[key]
+}
+
+// Get blocks until a key can be processed. The caller must call Done with
+// the key when processing is finished. shutdown is true if the queue was
+// shut down.
+func (q *WorkQueue) Get() (key string, shutdown bool) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+
+	for len(q.queue) == 0 && !q.shuttingDown {
+		q.cond.Wait()
+	}
+	if len(q.queue) == 0 {
+		return "", true
+	}
+
+	key, q.queue = q.queue[0], q.queue[1:]
+	q.processing[key] = struct{}{}
+	delete(q.dirty, key)
+	return key, false
+}
+
+// Done marks key as processed. If key was added while being processed it
+// is queued again.
+func (q *WorkQueue) Done(key string) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+
+	delete(q.processing, key)
+	if _, ok := q.dirty[key]; ok {
+		q.queue = append(q.queue, key)
+		q.cond.Signal()
+	}
+}
+
+// Len returns the number of keys waiting to be processed.
+func (q *WorkQueue) Len() int {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	return len(q.queue)
+}
+
+// ShutDown makes Get return once the queue is drained and ignores keys
+// added afterwards.
+func (q *WorkQueue) ShutDown() {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+
+	q.shuttingDown = true
+	q.cond.Broadcast()
+}
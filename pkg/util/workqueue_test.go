@@ -0,0 +1,63 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkQueueDeduplicates(t *testing.T) {
+	q := NewWorkQueue(time.Millisecond, time.Second)
+	q.Add("a")
+	q.Add("b")
+	q.Add("a")
+	assert.Equal(t, 2, q.Len())
+
+	key, shutdown := q.Get()
+	assert.Equal(t, "a", key)
+	assert.False(t, shutdown)
+
+	// Added while processing, queued again once done.
+	q.Add("a")
+	assert.Equal(t, 1, q.Len())
+	q.Done("a")
+	assert.Equal(t, 2, q.Len())
+
+	key, _ = q.Get()
+	assert.Equal(t, "b", key)
+	q.Done("b")
+	key, _ = q.Get()
+	assert.Equal(t, "a", key)
+	q.Done("a")
+	assert.Equal(t, 0, q.Len())
+}
+
+func TestWorkQueueShutDown(t *testing.T) {
+	q := NewWorkQueue(time.Millisecond, time.Second)
+	q.Add("a")
+	q.ShutDown()
+	q.Add("b")
+
+	key, shutdown := q.Get()
+	assert.Equal(t, "a", key)
+	assert.False(t, shutdown)
+
+	_, shutdown = q.Get()
+	assert.True(t, shutdown)
+}
+
+func TestWorkQueueBackoff(t *testing.T) {
+	q := NewWorkQueue(time.Second, 10*time.Second)
+	assert.Equal(t, time.Second, q.delay(0))
+	assert.Equal(t, 2*time.Second, q.delay(1))
+	assert.Equal(t, 8*time.Second, q.delay(3))
+	assert.Equal(t, 10*time.Second, q.delay(4))
+	assert.Equal(t, 10*time.Second, q.delay(100))
+
+	q.AddRateLimited("a")
+	q.AddRateLimited("a")
+	assert.Equal(t, 2, q.NumRequeues("a"))
+	q.Forget("a")
+	assert.Equal(t, 0, q.NumRequeues("a"))
+}